@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/input"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// Action types accepted in an actions list
+const (
+	ActionClick           = "click"
+	ActionType            = "type"
+	ActionPress           = "press"
+	ActionHover           = "hover"
+	ActionScrollTo        = "scroll_to"
+	ActionSelect          = "select"
+	ActionWait            = "wait"
+	ActionWaitForSelector = "wait_for_selector"
+)
+
+// Action is a single scripted interaction run after the page loads and before
+// capture
+type Action struct {
+	Type     string `json:"type" jsonschema:"one of 'click', 'type', 'press', 'hover', 'scroll_to', 'select', 'wait' or 'wait_for_selector'"`
+	Selector string `json:"selector,omitempty" jsonschema:"CSS selector of the target element, required for all types except 'press' and 'wait', optional for 'type' to type into the focused element"`
+	Text     string `json:"text,omitempty" jsonschema:"text to enter with 'type'"`
+	Key      string `json:"key,omitempty" jsonschema:"key to press with 'press', a single character or a name like 'Enter', 'Escape', 'Tab' or 'ArrowDown'"`
+	Value    string `json:"value,omitempty" jsonschema:"option value or visible text to choose with 'select'"`
+	Ms       int    `json:"ms,omitempty" jsonschema:"milliseconds to pause with 'wait'"`
+	State    string `json:"state,omitempty" jsonschema:"state for 'wait_for_selector': 'visible', 'hidden' or 'attached' (default: 'visible')"`
+}
+
+// namedKeys maps the key names accepted by press to rod keys. Single printable
+// characters are accepted as well.
+var namedKeys = map[string]input.Key{
+	"enter":      input.Enter,
+	"escape":     input.Escape,
+	"tab":        input.Tab,
+	"backspace":  input.Backspace,
+	"delete":     input.Delete,
+	"space":      input.Space,
+	"arrowup":    input.ArrowUp,
+	"arrowdown":  input.ArrowDown,
+	"arrowleft":  input.ArrowLeft,
+	"arrowright": input.ArrowRight,
+	"home":       input.Home,
+	"end":        input.End,
+	"pageup":     input.PageUp,
+	"pagedown":   input.PageDown,
+}
+
+// parseKey converts a key name or single printable character to a rod key
+func parseKey(name string) (input.Key, error) {
+	if key, exists := namedKeys[strings.ToLower(name)]; exists {
+		return key, nil
+	}
+
+	if len(name) == 1 && name[0] >= ' ' && name[0] <= '~' {
+		return input.Key(name[0]), nil
+	}
+
+	return 0, fmt.Errorf("unknown key %q", name)
+}
+
+// ParseActions decodes an actions list given either as a JSON array or as an
+// object with an "actions" array, and validates every step
+func ParseActions(data []byte) ([]Action, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	var actions []Action
+	if data[0] == '{' {
+		var body struct {
+			Actions []Action `json:"actions"`
+		}
+		if err := json.Unmarshal(data, &body); err != nil {
+			return nil, fmt.Errorf("invalid actions JSON: %v", err)
+		}
+		actions = body.Actions
+	} else if err := json.Unmarshal(data, &actions); err != nil {
+		return nil, fmt.Errorf("invalid actions JSON: %v", err)
+	}
+
+	if err := validateActions(actions); err != nil {
+		return nil, err
+	}
+
+	return actions, nil
+}
+
+// validateActions checks that every action has a known type and the fields it
+// needs, normalizing types and wait states in place
+func validateActions(actions []Action) error {
+	for i := range actions {
+		action := &actions[i]
+		action.Type = strings.ToLower(strings.TrimSpace(action.Type))
+
+		var err error
+		switch action.Type {
+		case ActionClick, ActionHover, ActionScrollTo:
+			if action.Selector == "" {
+				err = fmt.Errorf("selector is required")
+			}
+		case ActionType:
+			if action.Text == "" {
+				err = fmt.Errorf("text is required")
+			}
+		case ActionPress:
+			_, err = parseKey(action.Key)
+		case ActionSelect:
+			if action.Selector == "" {
+				err = fmt.Errorf("selector is required")
+			}
+		case ActionWait:
+			if action.Ms <= 0 {
+				err = fmt.Errorf("ms must be positive")
+			}
+		case ActionWaitForSelector:
+			if action.Selector == "" {
+				err = fmt.Errorf("selector is required")
+			} else {
+				action.State, err = normalizeWaitState(action.State)
+			}
+		default:
+			err = fmt.Errorf("unknown action type %q", action.Type)
+		}
+
+		if err != nil {
+			return fmt.Errorf("invalid action %d: %v", i+1, err)
+		}
+	}
+
+	return nil
+}
+
+// runActions performs the actions in order. Each step is bounded by the
+// request timeout, or defaultWaitTimeout if there is none.
+func runActions(page *rod.Page, actions []Action) error {
+	for i, action := range actions {
+		if err := runAction(page, action); err != nil {
+			return fmt.Errorf("action %d (%s) failed: %w", i+1, action.Type, err)
+		}
+	}
+
+	return nil
+}
+
+func runAction(page *rod.Page, action Action) error {
+	page, cancel := waitContext(page)
+	defer cancel()
+
+	switch action.Type {
+	case ActionPress:
+		key, err := parseKey(action.Key)
+		if err != nil {
+			return err
+		}
+		return page.Keyboard.Type(key)
+	case ActionWait:
+		select {
+		case <-page.GetContext().Done():
+			return page.GetContext().Err()
+		case <-time.After(time.Duration(action.Ms) * time.Millisecond):
+			return nil
+		}
+	case ActionWaitForSelector:
+		return waitForSelector(page, action.Selector, action.State)
+	case ActionType:
+		if action.Selector == "" {
+			return page.InsertText(action.Text)
+		}
+	}
+
+	// The remaining actions target an element, wait for it to appear
+	element, err := page.Element(action.Selector)
+	if err != nil {
+		return fmt.Errorf("no element matches selector %q: %w", action.Selector, err)
+	}
+
+	switch action.Type {
+	case ActionClick:
+		return element.Click(proto.InputMouseButtonLeft, 1)
+	case ActionType:
+		return element.Input(action.Text)
+	case ActionHover:
+		return element.Hover()
+	case ActionScrollTo:
+		return element.ScrollIntoView()
+	case ActionSelect:
+		return selectOption(element, action.Value)
+	default:
+		return fmt.Errorf("unknown action type %q", action.Type)
+	}
+}
+
+// selectOption chooses the option of a select element whose value or visible
+// text matches value, firing the events a user selection would
+func selectOption(element *rod.Element, value string) error {
+	result, err := element.Eval(`function (value) {
+		const option = Array.from(this.options || []).find((option) =>
+			option.value === value || option.textContent.trim() === value)
+		if (!option) {
+			return false
+		}
+
+		option.selected = true
+		this.dispatchEvent(new Event("input", {bubbles: true}))
+		this.dispatchEvent(new Event("change", {bubbles: true}))
+		return true
+	}`, value)
+	if err != nil {
+		return err
+	}
+
+	if !result.Value.Bool() {
+		return fmt.Errorf("no option matches %q", value)
+	}
+
+	return nil
+}
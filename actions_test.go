@@ -0,0 +1,128 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/go-rod/rod/lib/input"
+)
+
+func TestParseActionsFormats(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  string
+		count int
+	}{
+		{"array", `[{"type": "click", "selector": "#a"}, {"type": "wait", "ms": 100}]`, 2},
+		{"object", `{"actions": [{"type": "click", "selector": "#a"}]}`, 1},
+		{"object without actions", `{}`, 0},
+		{"empty", ``, 0},
+		{"whitespace", "  \n ", 0},
+	}
+
+	for _, tt := range tests {
+		actions, err := ParseActions([]byte(tt.data))
+		if err != nil {
+			t.Errorf("%s: expected no error, got %v", tt.name, err)
+			continue
+		}
+		if len(actions) != tt.count {
+			t.Errorf("%s: expected %d actions, got %d", tt.name, tt.count, len(actions))
+		}
+	}
+
+	for _, data := range []string{`[{"type": "click"`, `{"actions": {"type": "click"}}`, `"click"`} {
+		if _, err := ParseActions([]byte(data)); err == nil {
+			t.Errorf("Expected error for %s", data)
+		}
+	}
+}
+
+func TestParseActionsValidation(t *testing.T) {
+	tests := []struct {
+		action string
+		valid  bool
+	}{
+		{`{"type": "click", "selector": "#a"}`, true},
+		{`{"type": "click"}`, false},
+		{`{"type": "hover", "selector": "#a"}`, true},
+		{`{"type": "hover"}`, false},
+		{`{"type": "scroll_to", "selector": "#a"}`, true},
+		{`{"type": "scroll_to"}`, false},
+		{`{"type": "type", "text": "hello"}`, true},
+		{`{"type": "type", "selector": "input", "text": "hello"}`, true},
+		{`{"type": "type", "selector": "input"}`, false},
+		{`{"type": "press", "key": "Enter"}`, true},
+		{`{"type": "press", "key": "a"}`, true},
+		{`{"type": "press"}`, false},
+		{`{"type": "press", "key": "Hyper"}`, false},
+		{`{"type": "select", "selector": "select", "value": "b"}`, true},
+		{`{"type": "select", "value": "b"}`, false},
+		{`{"type": "wait", "ms": 10}`, true},
+		{`{"type": "wait"}`, false},
+		{`{"type": "wait", "ms": -5}`, false},
+		{`{"type": "wait_for_selector", "selector": "#a"}`, true},
+		{`{"type": "wait_for_selector", "selector": "#a", "state": "hidden"}`, true},
+		{`{"type": "wait_for_selector", "selector": "#a", "state": "gone"}`, false},
+		{`{"type": "wait_for_selector"}`, false},
+		{`{"type": "CLICK", "selector": "#a"}`, true},
+		{`{"type": "double_click", "selector": "#a"}`, false},
+		{`{"selector": "#a"}`, false},
+	}
+
+	for _, tt := range tests {
+		_, err := ParseActions([]byte("[" + tt.action + "]"))
+		if tt.valid && err != nil {
+			t.Errorf("%s: expected valid, got %v", tt.action, err)
+		} else if !tt.valid && err == nil {
+			t.Errorf("%s: expected error, got none", tt.action)
+		}
+	}
+}
+
+func TestParseActionsNormalizes(t *testing.T) {
+	actions, err := ParseActions([]byte(`[{"type": " Wait_For_Selector ", "selector": "#a"}]`))
+	if err != nil {
+		t.Fatalf("Expected actions to parse, got %v", err)
+	}
+
+	if actions[0].Type != ActionWaitForSelector {
+		t.Errorf("Expected type %q, got %q", ActionWaitForSelector, actions[0].Type)
+	}
+	if actions[0].State != WaitStateVisible {
+		t.Errorf("Expected default state %q, got %q", WaitStateVisible, actions[0].State)
+	}
+}
+
+func TestParseKey(t *testing.T) {
+	tests := []struct {
+		name string
+		key  input.Key
+	}{
+		{"Enter", input.Enter},
+		{"enter", input.Enter},
+		{"ESCAPE", input.Escape},
+		{"ArrowDown", input.ArrowDown},
+		{"PageUp", input.PageUp},
+		{"a", input.Key('a')},
+		{"Z", input.Key('Z')},
+		{"1", input.Key('1')},
+		{" ", input.Key(' ')},
+	}
+
+	for _, tt := range tests {
+		key, err := parseKey(tt.name)
+		if err != nil {
+			t.Errorf("parseKey(%q): expected no error, got %v", tt.name, err)
+			continue
+		}
+		if key != tt.key {
+			t.Errorf("parseKey(%q): expected %v, got %v", tt.name, tt.key, key)
+		}
+	}
+
+	for _, name := range []string{"", "ab", "Hyper", "é", "\t"} {
+		if _, err := parseKey(name); err == nil {
+			t.Errorf("parseKey(%q): expected error", name)
+		}
+	}
+}
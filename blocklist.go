@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// blockableResourceTypes maps the names accepted by block_resource_types to
+// the browser's resource types
+var blockableResourceTypes = map[string][]proto.NetworkResourceType{
+	"image":      {proto.NetworkResourceTypeImage},
+	"font":       {proto.NetworkResourceTypeFont},
+	"media":      {proto.NetworkResourceTypeMedia},
+	"stylesheet": {proto.NetworkResourceTypeStylesheet},
+	"script":     {proto.NetworkResourceTypeScript},
+	"xhr":        {proto.NetworkResourceTypeXHR, proto.NetworkResourceTypeFetch},
+	"websocket":  {proto.NetworkResourceTypeWebSocket},
+}
+
+// ParseResourceTypes parses a comma-separated list of resource type names
+func ParseResourceTypes(value string) ([]string, error) {
+	var types []string
+	for _, name := range strings.Split(value, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		if _, exists := blockableResourceTypes[name]; !exists {
+			return nil, fmt.Errorf("unknown resource type %q, must be one of image, font, media, stylesheet, script, xhr or websocket", name)
+		}
+
+		types = append(types, name)
+	}
+
+	return types, nil
+}
+
+// isBlockedResourceType reports whether a request's resource type is one of
+// the blocked type names
+func isBlockedResourceType(resourceType proto.NetworkResourceType, blocked []string) bool {
+	for _, name := range blocked {
+		for _, blockedType := range blockableResourceTypes[name] {
+			if resourceType == blockedType {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// AdblockList is a set of hosts loaded from an EasyList or hosts format file.
+// A host blocks its subdomains as well.
+type AdblockList struct {
+	Path  string
+	hosts map[string]struct{}
+}
+
+// LoadAdblockList reads the network blocking rules of an adblock list. Hosts
+// file lines ("0.0.0.0 ads.example.com"), plain domains and EasyList domain
+// anchors ("||ads.example.com^", optionally with $third-party) are used.
+// Cosmetic, exception, path and rules with other options are skipped.
+func LoadAdblockList(path string) (*AdblockList, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	list := &AdblockList{
+		Path:  path,
+		hosts: make(map[string]struct{}),
+	}
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		for _, host := range parseAdblockLine(scanner.Text()) {
+			list.hosts[host] = struct{}{}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read adblock list: %v", err)
+	}
+
+	if len(list.hosts) == 0 {
+		return nil, fmt.Errorf("no blocking rules found in %s", path)
+	}
+
+	return list, nil
+}
+
+// parseAdblockLine returns the hosts blocked by a line, nothing for comments
+// and rules that aren't plain host blocks
+func parseAdblockLine(line string) []string {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "!") || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "[") || strings.HasPrefix(line, "@@") {
+		return nil
+	}
+
+	// EasyList domain anchor, optionally with $third-party. Rules with any
+	// other option only apply to some requests, like $image or $popup, so
+	// blocking the whole host would break pages.
+	if strings.HasPrefix(line, "||") {
+		rule, options, hasOptions := strings.Cut(strings.TrimPrefix(line, "||"), "$")
+		if hasOptions {
+			for _, option := range strings.Split(options, ",") {
+				if strings.ToLower(strings.TrimSpace(option)) != "third-party" {
+					return nil
+				}
+			}
+		}
+
+		host, rest, _ := strings.Cut(rule, "^")
+		if rest != "" && rest != "|" {
+			return nil
+		}
+		return normalizeBlockedHosts([]string{host})
+	}
+
+	// Hosts file lines may end with a comment
+	if index := strings.Index(line, "#"); index > 0 && (line[index-1] == ' ' || line[index-1] == '\t') {
+		line = strings.TrimSpace(line[:index])
+	}
+
+	// Cosmetic rules and other EasyList syntax
+	if strings.ContainsAny(line, "#$|^*/") {
+		return nil
+	}
+
+	// A plain domain, or a hosts file address followed by hosts
+	fields := strings.Fields(line)
+	if len(fields) == 1 {
+		return normalizeBlockedHosts(fields)
+	}
+	return normalizeBlockedHosts(fields[1:])
+}
+
+// normalizeBlockedHosts lowercases hosts, dropping addresses, local names and
+// anything that isn't a hostname
+func normalizeBlockedHosts(hosts []string) []string {
+	var normalized []string
+	for _, host := range hosts {
+		host = strings.ToLower(strings.TrimSuffix(host, "."))
+		if !strings.Contains(host, ".") || strings.ContainsAny(host, "*/:") || host == "localhost.localdomain" {
+			continue
+		}
+		if _, err := netip.ParseAddr(host); err == nil {
+			continue
+		}
+		normalized = append(normalized, host)
+	}
+	return normalized
+}
+
+// Blocks reports whether hostname or one of its parent domains is listed
+func (l *AdblockList) Blocks(hostname string) bool {
+	if l == nil {
+		return false
+	}
+
+	hostname = strings.ToLower(hostname)
+	for {
+		if _, exists := l.hosts[hostname]; exists {
+			return true
+		}
+
+		_, parent, found := strings.Cut(hostname, ".")
+		if !found {
+			return false
+		}
+		hostname = parent
+	}
+}
+
+// Len returns the number of blocked hosts
+func (l *AdblockList) Len() int {
+	return len(l.hosts)
+}
+
+// blockReason returns why the request is blocked by the block domains,
+// resource types or adblock list, or "" if it isn't
+func blockReason(requestURL string, resourceType proto.NetworkResourceType, config *HijackConfig) string {
+	if isBlockedResourceType(resourceType, config.BlockResourceTypes) {
+		return fmt.Sprintf("resource type %s is blocked", strings.ToLower(string(resourceType)))
+	}
+
+	if len(config.BlockDomains) == 0 && config.AdblockList == nil {
+		return ""
+	}
+
+	parsed, err := url.Parse(requestURL)
+	if err != nil {
+		return ""
+	}
+	hostname := parsed.Hostname()
+
+	if matchesDomainPattern(hostname, config.BlockDomains) {
+		return "domain is blocked"
+	}
+
+	if config.AdblockList.Blocks(hostname) {
+		return "domain is in adblock list"
+	}
+
+	return ""
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestParseAdblockLine(t *testing.T) {
+	tests := []struct {
+		line  string
+		hosts []string
+	}{
+		{"0.0.0.0 ads.example.com", []string{"ads.example.com"}},
+		{"127.0.0.1\ttracker.example.com", []string{"tracker.example.com"}},
+		{"0.0.0.0 a.example.com b.example.com", []string{"a.example.com", "b.example.com"}},
+		{"0.0.0.0 ads.example.com # ad server", []string{"ads.example.com"}},
+		{"0.0.0.0 ads.example.com\t# ad server", []string{"ads.example.com"}},
+		{"0.0.0.0 ADS.Example.COM.", []string{"ads.example.com"}},
+		{"127.0.0.1 localhost", nil},
+		{"::1 localhost", nil},
+		{"0.0.0.0 0.0.0.0", nil},
+		{"ads.example.com", []string{"ads.example.com"}},
+		{"||ads.example.com^", []string{"ads.example.com"}},
+		{"||ads.example.com^|", []string{"ads.example.com"}},
+		{"||ads.example.com^$third-party", []string{"ads.example.com"}},
+		{"||ads.example.com^$Third-Party", []string{"ads.example.com"}},
+		{"||ads.example.com^$popup", nil},
+		{"||ads.example.com^$image", nil},
+		{"||ads.example.com^$script,third-party", nil},
+		{"||ads.example.com^$~third-party", nil},
+		{"||ads.example.com^$domain=news.example.com", nil},
+		{"||ads.example.com/banner.js", nil},
+		{"||ads.example.com^*/banner", nil},
+		{"@@||ads.example.com^", nil},
+		{"example.com##.ad-banner", nil},
+		{"##.ad-banner", nil},
+		{"example.com#@#.ad-banner", nil},
+		{"/banner/*/ad.js", nil},
+		{"! Title: EasyList", nil},
+		{"# hosts comment", nil},
+		{"[Adblock Plus 2.0]", nil},
+		{"", nil},
+	}
+
+	for _, tt := range tests {
+		if got := parseAdblockLine(tt.line); !slices.Equal(got, tt.hosts) {
+			t.Errorf("parseAdblockLine(%q): expected %v, got %v", tt.line, tt.hosts, got)
+		}
+	}
+}
+
+func TestAdblockListBlocks(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "list.txt")
+	data := "! comment\n||ads.example.com^\n0.0.0.0 tracker.net\n||popup.example.com^$popup\n"
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := LoadAdblockList(path)
+	if err != nil {
+		t.Fatalf("Expected list to load, got %v", err)
+	}
+	if list.Len() != 2 {
+		t.Errorf("Expected 2 hosts, got %d", list.Len())
+	}
+
+	tests := []struct {
+		hostname string
+		blocked  bool
+	}{
+		{"ads.example.com", true},
+		{"cdn.ads.example.com", true},
+		{"a.b.ads.example.com", true},
+		{"ADS.EXAMPLE.COM", true},
+		{"tracker.net", true},
+		{"pixel.tracker.net", true},
+		{"example.com", false},
+		{"www.example.com", false},
+		{"notads.example.com", false},
+		{"popup.example.com", false},
+		{"net", false},
+	}
+
+	for _, tt := range tests {
+		if got := list.Blocks(tt.hostname); got != tt.blocked {
+			t.Errorf("Blocks(%q): expected %v, got %v", tt.hostname, tt.blocked, got)
+		}
+	}
+
+	var nilList *AdblockList
+	if nilList.Blocks("ads.example.com") {
+		t.Error("Expected nil list to block nothing")
+	}
+}
@@ -0,0 +1,395 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/cdp"
+	"github.com/go-rod/rod/lib/launcher"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+const (
+	browserHealthCheckInterval = 30 * time.Second
+	browserConnectTimeout      = 10 * time.Second
+	browserPoolIdleTimeout     = 10 * time.Minute
+	maxBrowserPools            = 8
+)
+
+// browserPools holds one pool per browser URL (empty string for locally
+// launched browsers) and is shared by the HTTP and MCP servers. When nil
+// (command line mode) every request launches and closes its own browser.
+var (
+	browserPools      map[string]*BrowserPool
+	browserPoolsMutex sync.Mutex
+)
+
+// BrowserPool keeps a fixed number of long-lived browser connections open and
+// hands out a fresh incognito context from one of them for every request
+type BrowserPool struct {
+	browserURL string
+	slots      []*browserSlot
+	next       atomic.Uint64
+	stop       chan struct{}
+	closeOnce  sync.Once
+	users      int       // Requests using the pool, guarded by browserPoolsMutex
+	lastUsed   time.Time // Guarded by browserPoolsMutex
+}
+
+// browserSlot holds a single browser connection, opened lazily on first use
+// and replaced whenever it stops responding
+type browserSlot struct {
+	browserURL string
+	browser    *rod.Browser
+	close      func()
+	mutex      sync.Mutex
+}
+
+func NewBrowserPool(browserURL string, size int) *BrowserPool {
+	if size < 1 {
+		size = 1
+	}
+
+	pool := &BrowserPool{
+		browserURL: browserURL,
+		slots:      make([]*browserSlot, size),
+		stop:       make(chan struct{}),
+		lastUsed:   time.Now(),
+	}
+
+	for i := range pool.slots {
+		pool.slots[i] = &browserSlot{browserURL: browserURL}
+	}
+
+	go pool.healthCheckLoop(browserHealthCheckInterval)
+
+	return pool
+}
+
+// initBrowserPools enables browser pooling for the server modes
+func initBrowserPools() {
+	browserPoolsMutex.Lock()
+	defer browserPoolsMutex.Unlock()
+
+	if browserPools == nil {
+		browserPools = make(map[string]*BrowserPool)
+	}
+}
+
+// getBrowserPool returns the pool for the given browser URL, creating it on
+// first use. Returns nil if pooling is not enabled. Each pool returned must be
+// handed back to releaseBrowserPool.
+func getBrowserPool(browserURL string) (*BrowserPool, error) {
+	browserPoolsMutex.Lock()
+	defer browserPoolsMutex.Unlock()
+
+	if browserPools == nil {
+		return nil, nil
+	}
+
+	pool, exists := browserPools[browserURL]
+	if !exists {
+		if len(browserPools) >= maxBrowserPools && !closeLeastRecentBrowserPool() {
+			return nil, fmt.Errorf("too many browser URLs in use (limit %d)", maxBrowserPools)
+		}
+		pool = NewBrowserPool(browserURL, globalPoolSize)
+		browserPools[browserURL] = pool
+	}
+
+	pool.users++
+	return pool, nil
+}
+
+// releaseBrowserPool marks a request returned by getBrowserPool as finished
+func releaseBrowserPool(pool *BrowserPool) {
+	browserPoolsMutex.Lock()
+	defer browserPoolsMutex.Unlock()
+
+	pool.users--
+	pool.lastUsed = time.Now()
+}
+
+// closeLeastRecentBrowserPool closes the least recently used pool that isn't
+// in use, other than the one for --browser-url. Returns false if there is
+// none. Must be called with browserPoolsMutex held.
+func closeLeastRecentBrowserPool() bool {
+	var oldest *BrowserPool
+	for _, pool := range browserPools {
+		if pool.users > 0 || pool.browserURL == globalBrowserURL {
+			continue
+		}
+		if oldest == nil || pool.lastUsed.Before(oldest.lastUsed) {
+			oldest = pool
+		}
+	}
+
+	if oldest == nil {
+		return false
+	}
+
+	delete(browserPools, oldest.browserURL)
+	go oldest.Close()
+	return true
+}
+
+// closeIfIdle closes the pool once nothing has used it for timeout. The pool
+// for --browser-url is kept open.
+func (p *BrowserPool) closeIfIdle(timeout time.Duration) bool {
+	browserPoolsMutex.Lock()
+	if p.users > 0 || p.browserURL == globalBrowserURL || time.Since(p.lastUsed) < timeout {
+		browserPoolsMutex.Unlock()
+		return false
+	}
+	if browserPools[p.browserURL] == p {
+		delete(browserPools, p.browserURL)
+	}
+	browserPoolsMutex.Unlock()
+
+	log.Printf("Closing browser pool for %q after %v unused", p.browserURL, timeout)
+	p.Close()
+	return true
+}
+
+// connectBrowser launches a local browser, or connects to an already running
+// one when browserURL is set. The returned function shuts down a local browser
+// but only disconnects from a remote one, since it may be shared.
+func connectBrowser(browserURL string) (*rod.Browser, func(), error) {
+	if browserURL == "" {
+		browser := rod.New()
+		if err := browser.Connect(); err != nil {
+			return nil, nil, err
+		}
+		return browser, func() { closeBrowser(browser) }, nil
+	}
+
+	wsURL := browserURL
+	if !strings.Contains(browserURL, "/devtools/browser/") {
+		resolved, err := launcher.ResolveURL(browserURL)
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to reach browser at %s: %w", browserURL, err)
+		}
+		wsURL = resolved
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), browserConnectTimeout)
+	defer cancel()
+
+	ws := &cdp.WebSocket{}
+	if err := ws.Connect(ctx, wsURL, nil); err != nil {
+		return nil, nil, fmt.Errorf("unable to connect to browser at %s: %w", browserURL, err)
+	}
+
+	browser := rod.New().Client(cdp.New().Start(ws))
+	if err := browser.Connect(); err != nil {
+		ws.Close()
+		return nil, nil, fmt.Errorf("unable to connect to browser at %s: %w", browserURL, err)
+	}
+
+	disconnect := func() {
+		if err := ws.Close(); err != nil {
+			log.Printf("Error disconnecting from browser at %s: %v", browserURL, err)
+		}
+	}
+
+	return browser, disconnect, nil
+}
+
+func closeBrowser(browser *rod.Browser) {
+	if err := browser.Close(); err != nil {
+		log.Printf("Error closing browser: %v", err)
+	}
+}
+
+// get returns the slot's browser, connecting a new one if the slot is empty
+func (s *browserSlot) get() (*rod.Browser, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.browser == nil {
+		browser, closeFn, err := connectBrowser(s.browserURL)
+		if err != nil {
+			return nil, err
+		}
+		s.browser = browser
+		s.close = closeFn
+	}
+
+	return s.browser, nil
+}
+
+// discard drops the browser from the slot so the next get connects a
+// replacement. It is a no-op if the slot already holds a different browser.
+func (s *browserSlot) discard(browser *rod.Browser) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.browser != browser {
+		return
+	}
+
+	go s.close()
+	s.browser = nil
+	s.close = nil
+}
+
+// healthy pings the slot's browser, returning true for an empty slot
+func (s *browserSlot) healthy() (*rod.Browser, bool) {
+	s.mutex.Lock()
+	browser := s.browser
+	s.mutex.Unlock()
+
+	if browser == nil {
+		return nil, true
+	}
+
+	_, err := proto.BrowserGetVersion{}.Call(browser.Timeout(5 * time.Second))
+	return browser, err == nil
+}
+
+// Acquire returns a new incognito browser context from the next browser in
+// the pool, using proxyServer when set, along with a release function that
+// disposes of it. A browser that fails to create a context is treated as
+// crashed and replaced.
+func (p *BrowserPool) Acquire(proxyServer string) (*rod.Browser, func(), error) {
+	slot := p.slots[p.next.Add(1)%uint64(len(p.slots))]
+
+	var lastErr error
+	for attempt := 0; attempt < 2; attempt++ {
+		browser, err := slot.get()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		incognito, err := newBrowserContext(browser, proxyServer)
+		if err != nil {
+			log.Printf("Browser failed to create context, reconnecting: %v", err)
+			slot.discard(browser)
+			lastErr = err
+			continue
+		}
+
+		release := func() {
+			if err := incognito.Close(); err != nil {
+				log.Printf("Error closing browser context: %v", err)
+			}
+		}
+
+		return incognito, release, nil
+	}
+
+	return nil, nil, fmt.Errorf("failed to create browser context: %w", lastErr)
+}
+
+func (p *BrowserPool) healthCheckLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			if p.closeIfIdle(browserPoolIdleTimeout) {
+				return
+			}
+			p.HealthCheck()
+		}
+	}
+}
+
+// HealthCheck pings every running browser and discards the ones that don't
+// respond so they get relaunched on next use
+func (p *BrowserPool) HealthCheck() {
+	for i, slot := range p.slots {
+		if browser, ok := slot.healthy(); !ok {
+			log.Printf("Browser %d failed health check, reconnecting", i)
+			slot.discard(browser)
+		}
+	}
+}
+
+// Close stops the health checks and shuts down every browser in the pool
+func (p *BrowserPool) Close() {
+	p.closeOnce.Do(func() {
+		close(p.stop)
+		for _, slot := range p.slots {
+			slot.mutex.Lock()
+			if slot.browser != nil {
+				slot.close()
+				slot.browser = nil
+				slot.close = nil
+			}
+			slot.mutex.Unlock()
+		}
+	})
+}
+
+// acquireBrowser returns a browser to run a single request in along with a
+// function to release it. Without pooling a dedicated browser connection is
+// opened, using an incognito context when connecting to a shared remote browser
+// or going through a proxy.
+func acquireBrowser(browserURL string, proxy *Proxy) (*rod.Browser, func(), error) {
+	proxyServer, releaseProxy, err := proxy.server(browserURL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	browser, release, err := acquireBrowserContext(browserURL, proxyServer)
+	if err != nil {
+		releaseProxy()
+		return nil, nil, err
+	}
+
+	return browser, func() {
+		release()
+		releaseProxy()
+	}, nil
+}
+
+func acquireBrowserContext(browserURL, proxyServer string) (*rod.Browser, func(), error) {
+	pool, err := getBrowserPool(browserURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	if pool != nil {
+		browser, release, err := pool.Acquire(proxyServer)
+		if err != nil {
+			releaseBrowserPool(pool)
+			return nil, nil, err
+		}
+		return browser, func() {
+			release()
+			releaseBrowserPool(pool)
+		}, nil
+	}
+
+	browser, closeFn, err := connectBrowser(browserURL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if browserURL == "" && proxyServer == "" {
+		return browser, closeFn, nil
+	}
+
+	incognito, err := newBrowserContext(browser, proxyServer)
+	if err != nil {
+		closeFn()
+		return nil, nil, fmt.Errorf("failed to create browser context: %w", err)
+	}
+
+	release := func() {
+		if err := incognito.Close(); err != nil {
+			log.Printf("Error closing browser context: %v", err)
+		}
+		closeFn()
+	}
+
+	return incognito, release, nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func withBrowserPools(t *testing.T) {
+	t.Helper()
+	browserPools = make(map[string]*BrowserPool)
+	t.Cleanup(func() {
+		for _, pool := range browserPools {
+			pool.Close()
+		}
+		browserPools = nil
+	})
+}
+
+func TestGetBrowserPoolLimit(t *testing.T) {
+	withBrowserPools(t)
+
+	pools := make([]*BrowserPool, 0, maxBrowserPools)
+	for i := 0; i < maxBrowserPools; i++ {
+		pool, err := getBrowserPool(fmt.Sprintf("ws://chrome-%d:9222", i))
+		if err != nil {
+			t.Fatalf("Expected pool %d to be created, got %v", i, err)
+		}
+		pools = append(pools, pool)
+	}
+
+	if _, err := getBrowserPool("ws://chrome-extra:9222"); err == nil {
+		t.Error("Expected an error when every pool is in use, got nil")
+	}
+
+	// Releasing a pool lets it be replaced
+	releaseBrowserPool(pools[3])
+	if _, err := getBrowserPool("ws://chrome-extra:9222"); err != nil {
+		t.Errorf("Expected the unused pool to be replaced, got %v", err)
+	}
+	if _, exists := browserPools[pools[3].browserURL]; exists {
+		t.Errorf("Expected pool for %s to be closed", pools[3].browserURL)
+	}
+	if len(browserPools) != maxBrowserPools {
+		t.Errorf("Expected %d pools, got %d", maxBrowserPools, len(browserPools))
+	}
+}
+
+func TestBrowserPoolCloseIfIdle(t *testing.T) {
+	withBrowserPools(t)
+
+	pool, err := getBrowserPool("ws://chrome:9222")
+	if err != nil {
+		t.Fatalf("Expected pool to be created, got %v", err)
+	}
+
+	if pool.closeIfIdle(0) {
+		t.Error("Expected a pool in use to stay open")
+	}
+
+	releaseBrowserPool(pool)
+	if pool.closeIfIdle(time.Hour) {
+		t.Error("Expected a recently used pool to stay open")
+	}
+	if !pool.closeIfIdle(0) {
+		t.Error("Expected an unused pool to be closed")
+	}
+	if _, exists := browserPools["ws://chrome:9222"]; exists {
+		t.Error("Expected closed pool to be removed")
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+type elementBox struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+// elementClip returns the document relative bounding box of the first element
+// matching selector, expanded by padding CSS pixels on every side
+func elementClip(page *rod.Page, selector string, padding int) (*proto.PageViewport, error) {
+	elements, err := page.Elements(selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector %q: %w", selector, err)
+	}
+
+	if len(elements) == 0 {
+		return nil, fmt.Errorf("no element matches selector %q", selector)
+	}
+
+	result, err := elements[0].Eval(`() => {
+		const rect = this.getBoundingClientRect()
+		return {x: rect.left + window.scrollX, y: rect.top + window.scrollY, width: rect.width, height: rect.height}
+	}`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bounding box for selector %q: %w", selector, err)
+	}
+
+	var box elementBox
+	if err := result.Value.Unmarshal(&box); err != nil {
+		return nil, fmt.Errorf("failed to get bounding box for selector %q: %w", selector, err)
+	}
+
+	if box.Width <= 0 || box.Height <= 0 {
+		return nil, fmt.Errorf("element matching selector %q has zero size", selector)
+	}
+
+	pad := float64(padding)
+	x := math.Max(0, box.X-pad)
+	y := math.Max(0, box.Y-pad)
+
+	return &proto.PageViewport{
+		X:      x,
+		Y:      y,
+		Width:  box.X + box.Width + pad - x,
+		Height: box.Y + box.Height + pad - y,
+		Scale:  1,
+	}, nil
+}
+
+// ParseClipString parses a clip rectangle in the format x,y,w,h (CSS pixels)
+func ParseClipString(clip string) (*proto.PageViewport, error) {
+	if clip == "" {
+		return nil, nil
+	}
+
+	parts := strings.Split(clip, ",")
+	if len(parts) != 4 {
+		return nil, fmt.Errorf("invalid clip format, expected x,y,w,h")
+	}
+
+	values := make([]int, 4)
+	for i, part := range parts {
+		value, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return nil, fmt.Errorf("invalid clip value %q, must be a number", part)
+		}
+		values[i] = value
+	}
+
+	if values[0] < 0 || values[1] < 0 {
+		return nil, fmt.Errorf("invalid clip position, x and y cannot be negative")
+	}
+
+	if values[2] <= 0 {
+		return nil, fmt.Errorf("invalid clip width")
+	}
+
+	if values[3] <= 0 {
+		return nil, fmt.Errorf("invalid clip height")
+	}
+
+	return &proto.PageViewport{
+		X:      float64(values[0]),
+		Y:      float64(values[1]),
+		Width:  float64(values[2]),
+		Height: float64(values[3]),
+		Scale:  1,
+	}, nil
+}
+
+func parsePaddingString(padding string) (int, error) {
+	if padding == "" {
+		return 0, nil
+	}
+
+	value, err := strconv.Atoi(padding)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid padding value, must be a non-negative number")
+	}
+
+	return value, nil
+}
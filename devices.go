@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DevicePreset describes a device to emulate. Applying a preset sets the
+// viewport, scale factor, mobile flag, touch emulation and user agent together.
+type DevicePreset struct {
+	Name      string  `json:"name"`
+	Width     int     `json:"width"`
+	Height    int     `json:"height"`
+	Scale     float64 `json:"scale"`
+	Mobile    bool    `json:"mobile"`
+	Touch     bool    `json:"touch"`
+	UserAgent string  `json:"user_agent"`
+}
+
+const (
+	iOSUserAgent    = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1"
+	iPadUserAgent   = "Mozilla/5.0 (iPad; CPU OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1"
+	pixelUserAgent  = "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Mobile Safari/537.36"
+	galaxyUserAgent = "Mozilla/5.0 (Linux; Android 14; SM-S911B) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Mobile Safari/537.36"
+)
+
+// emulatedTouchPoints is the number of touch points reported when touch
+// emulation is enabled
+const emulatedTouchPoints = 5
+
+var (
+	devicePresets = map[string]*DevicePreset{}
+	devicesMutex  sync.RWMutex
+)
+
+func init() {
+	for _, preset := range []*DevicePreset{
+		{Name: "iPhone SE", Width: 375, Height: 667, Scale: 2, Mobile: true, Touch: true, UserAgent: iOSUserAgent},
+		{Name: "iPhone 15", Width: 393, Height: 852, Scale: 3, Mobile: true, Touch: true, UserAgent: iOSUserAgent},
+		{Name: "iPhone 15 Pro Max", Width: 430, Height: 932, Scale: 3, Mobile: true, Touch: true, UserAgent: iOSUserAgent},
+		{Name: "Pixel 8", Width: 412, Height: 915, Scale: 2.625, Mobile: true, Touch: true, UserAgent: pixelUserAgent},
+		{Name: "Galaxy S23", Width: 360, Height: 780, Scale: 3, Mobile: true, Touch: true, UserAgent: galaxyUserAgent},
+		{Name: "iPad", Width: 820, Height: 1180, Scale: 2, Mobile: true, Touch: true, UserAgent: iPadUserAgent},
+		{Name: "iPad Pro", Width: 1024, Height: 1366, Scale: 2, Mobile: true, Touch: true, UserAgent: iPadUserAgent},
+	} {
+		devicePresets[normalizeDeviceName(preset.Name)] = preset
+	}
+}
+
+// normalizeDeviceName makes device lookups ignore case, spaces, dashes and
+// underscores so "iPhone 15", "iphone-15" and "iphone_15" all match
+func normalizeDeviceName(name string) string {
+	name = strings.ToLower(name)
+	return strings.NewReplacer(" ", "", "-", "", "_", "").Replace(name)
+}
+
+// LookupDevice returns the device preset with the given name
+func LookupDevice(name string) (*DevicePreset, error) {
+	devicesMutex.RLock()
+	defer devicesMutex.RUnlock()
+
+	preset, exists := devicePresets[normalizeDeviceName(name)]
+	if !exists {
+		return nil, fmt.Errorf("unknown device: %s (available: %s)", name, strings.Join(deviceNames(), ", "))
+	}
+
+	return preset, nil
+}
+
+// deviceNames returns the sorted names of all device presets, callers must
+// hold devicesMutex
+func deviceNames() []string {
+	names := make([]string, 0, len(devicePresets))
+	for _, preset := range devicePresets {
+		names = append(names, preset.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadDevicePresets adds presets from a JSON file mapping device names to
+// their settings, overriding built-in presets with the same name
+func LoadDevicePresets(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var presets map[string]*DevicePreset
+	if err := json.Unmarshal(data, &presets); err != nil {
+		return fmt.Errorf("invalid JSON format: %v", err)
+	}
+
+	devicesMutex.Lock()
+	defer devicesMutex.Unlock()
+
+	for name, preset := range presets {
+		if preset == nil || preset.Width <= 0 || preset.Height <= 0 {
+			return fmt.Errorf("device %s: width and height must be positive", name)
+		}
+		if err := validateScale(preset.Scale); err != nil {
+			return fmt.Errorf("device %s: %v", name, err)
+		}
+		preset.Name = name
+		devicePresets[normalizeDeviceName(name)] = preset
+	}
+
+	return nil
+}
+
+// Apply sets the viewport, scale and user agent from the preset where the
+// config doesn't already specify them, along with the mobile and touch settings
+func (d *DevicePreset) Apply(config *RequestConfig) {
+	if config.ViewportWidth == 0 || config.ViewportHeight == 0 {
+		config.ViewportWidth = d.Width
+		config.ViewportHeight = d.Height
+	}
+
+	if config.Scale == 0 {
+		config.Scale = d.Scale
+	}
+
+	config.Mobile = d.Mobile
+	config.Touch = d.Touch
+	if config.UserAgent == "" {
+		config.UserAgent = d.UserAgent
+	}
+}
@@ -34,9 +34,12 @@ func isDomainWhitelisted(requestURL string, whitelist []string) bool {
 		return false
 	}
 
-	hostname := parsed.Hostname()
+	return matchesDomainPattern(parsed.Hostname(), whitelist)
+}
 
-	for _, pattern := range whitelist {
+// matchesDomainPattern reports whether hostname matches any of the patterns
+func matchesDomainPattern(hostname string, patterns []string) bool {
+	for _, pattern := range patterns {
 		// Support glob patterns like *.example.com
 		matched, err := filepath.Match(pattern, hostname)
 		if err != nil {
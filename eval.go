@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// ScriptError is an exception thrown by an evaluated expression. Line and
+// column are 1-based positions in the expression.
+type ScriptError struct {
+	Message string `json:"message"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+}
+
+func (e *ScriptError) Error() string {
+	return fmt.Sprintf("%s (line %d, column %d)", e.Message, e.Line, e.Column)
+}
+
+func newScriptError(details *proto.RuntimeExceptionDetails) *ScriptError {
+	return &ScriptError{
+		Message: exceptionMessage(details),
+		Line:    details.LineNumber + 1,
+		Column:  details.ColumnNumber + 1,
+	}
+}
+
+// evaluateExpression evaluates a JavaScript expression in the page, awaiting
+// a returned promise, and returns the result serialized as JSON. Exceptions
+// thrown by the expression are returned as a *ScriptError.
+func evaluateExpression(page *rod.Page, expression string) (json.RawMessage, error) {
+	result, err := proto.RuntimeEvaluate{
+		Expression:    expression,
+		AwaitPromise:  true,
+		ReturnByValue: true,
+	}.Call(page)
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate expression: %w", err)
+	}
+
+	if result.ExceptionDetails != nil {
+		return nil, newScriptError(result.ExceptionDetails)
+	}
+
+	// undefined has no JSON form, report it as null
+	if result.Result.Type == proto.RuntimeRemoteObjectTypeUndefined {
+		return json.RawMessage("null"), nil
+	}
+
+	return json.RawMessage(result.Result.Value.JSON("", "")), nil
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// HeaderRule adds headers only to the requests whose host matches one of its
+// domains, so a token isn't sent to every third party host the page loads
+type HeaderRule struct {
+	Domains          []string          `json:"domains,omitempty" jsonschema:"host patterns the headers are sent to, with the same syntax as domains, e.g. 'api.example.com' or '*.example.com'. Required unless first_request_only is set"`
+	Headers          map[string]string `json:"headers" jsonschema:"HTTP headers to add to matching requests"`
+	FirstRequestOnly bool              `json:"first_request_only,omitempty" jsonschema:"only send the headers with the request for the page URL itself, not with redirects or subresources"`
+}
+
+// ParseHeaderRules decodes a JSON array of header rules
+func ParseHeaderRules(data string) ([]HeaderRule, error) {
+	if strings.TrimSpace(data) == "" {
+		return nil, nil
+	}
+
+	var rules []HeaderRule
+	if err := json.Unmarshal([]byte(data), &rules); err != nil {
+		return nil, fmt.Errorf("invalid JSON format: %v", err)
+	}
+
+	if err := validateHeaderRules(rules); err != nil {
+		return nil, err
+	}
+
+	return rules, nil
+}
+
+// validateHeaderRules checks that every rule has headers and valid patterns.
+// A rule without domains would send its headers to every third party host, so
+// it is only accepted for the first request.
+func validateHeaderRules(rules []HeaderRule) error {
+	for i, rule := range rules {
+		if len(rule.Headers) == 0 {
+			return fmt.Errorf("header rule %d has no headers", i+1)
+		}
+
+		if len(rule.Domains) == 0 && !rule.FirstRequestOnly {
+			return fmt.Errorf("header rule %d needs domains unless first_request_only is set", i+1)
+		}
+
+		for _, pattern := range rule.Domains {
+			if _, err := filepath.Match(pattern, ""); err != nil {
+				return fmt.Errorf("header rule %d has invalid domain pattern %q", i+1, pattern)
+			}
+		}
+	}
+
+	return nil
+}
+
+// requestHeaders returns the headers to add to a request: the unscoped custom
+// headers followed by those of every matching rule, later values winning.
+// firstRequest is set for the request loading the page URL.
+func requestHeaders(requestURL string, firstRequest bool, customHeaders map[string]string, rules []HeaderRule) map[string]string {
+	headers := make(map[string]string)
+
+	for name, value := range customHeaders {
+		headers[name] = value
+	}
+
+	for _, rule := range rules {
+		if rule.FirstRequestOnly && !firstRequest {
+			continue
+		}
+		if !isDomainWhitelisted(requestURL, rule.Domains) {
+			continue
+		}
+		for name, value := range rule.Headers {
+			headers[name] = value
+		}
+	}
+
+	return headers
+}
+
+// continueWithHeaders sends a hijacked request on with headers added to the
+// ones the browser set
+func continueWithHeaders(ctx *rod.Hijack, headers map[string]string, debug bool) {
+	if len(headers) == 0 {
+		ctx.ContinueRequest(&proto.FetchContinueRequest{})
+		return
+	}
+
+	entries := mergeHeaders(ctx.Request.Req().Header, headers)
+
+	if debug {
+		names := make([]string, 0, len(headers))
+		for name := range headers {
+			names = append(names, name)
+		}
+		log.Printf("\033[35mAdding custom headers:\033[0m %s to %s", strings.Join(names, ", "), ctx.Request.URL())
+	}
+
+	ctx.ContinueRequest(&proto.FetchContinueRequest{
+		Headers: entries,
+	})
+}
+
+// mergeHeaders returns the request's headers with headers added. Added headers
+// replace existing ones with the same name, compared case-insensitively.
+func mergeHeaders(existing http.Header, headers map[string]string) []*proto.FetchHeaderEntry {
+	var entries []*proto.FetchHeaderEntry
+	for name, values := range existing {
+		if _, replaced := lookupHeader(headers, name); replaced {
+			continue
+		}
+		for _, value := range values {
+			entries = append(entries, &proto.FetchHeaderEntry{
+				Name:  name,
+				Value: value,
+			})
+		}
+	}
+	for name, value := range headers {
+		entries = append(entries, &proto.FetchHeaderEntry{
+			Name:  name,
+			Value: value,
+		})
+	}
+
+	return entries
+}
+
+// lookupHeader finds a header by case-insensitive name
+func lookupHeader(headers map[string]string, name string) (string, bool) {
+	for key, value := range headers {
+		if strings.EqualFold(key, name) {
+			return value, true
+		}
+	}
+	return "", false
+}
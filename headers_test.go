@@ -0,0 +1,88 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestValidateHeaderRules(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules []HeaderRule
+		valid bool
+	}{
+		{"scoped", []HeaderRule{{Domains: []string{"api.example.com"}, Headers: map[string]string{"X-Key": "secret"}}}, true},
+		{"first request only without domains", []HeaderRule{{Headers: map[string]string{"Authorization": "Bearer token"}, FirstRequestOnly: true}}, true},
+		{"no domains", []HeaderRule{{Headers: map[string]string{"Authorization": "Bearer token"}}}, false},
+		{"no headers", []HeaderRule{{Domains: []string{"example.com"}}}, false},
+		{"invalid pattern", []HeaderRule{{Domains: []string{"[example.com"}, Headers: map[string]string{"X-Key": "secret"}}}, false},
+	}
+
+	for _, tt := range tests {
+		err := validateHeaderRules(tt.rules)
+		if tt.valid && err != nil {
+			t.Errorf("%s: expected valid, got %v", tt.name, err)
+		} else if !tt.valid && err == nil {
+			t.Errorf("%s: expected error", tt.name)
+		}
+	}
+}
+
+func TestRequestHeaders(t *testing.T) {
+	custom := map[string]string{"X-Custom": "all", "X-Shared": "custom"}
+	rules := []HeaderRule{
+		{Domains: []string{"api.example.com"}, Headers: map[string]string{"X-Key": "api"}},
+		{Domains: []string{"*.cdn.example.com"}, Headers: map[string]string{"X-Shared": "cdn"}},
+		{Headers: map[string]string{"Authorization": "Bearer token"}, FirstRequestOnly: true},
+	}
+
+	tests := []struct {
+		name     string
+		url      string
+		first    bool
+		expected map[string]string
+	}{
+		{"first request", "https://example.com/", true, map[string]string{"X-Custom": "all", "X-Shared": "custom", "Authorization": "Bearer token"}},
+		{"third party", "https://tracker.example.net/pixel", false, map[string]string{"X-Custom": "all", "X-Shared": "custom"}},
+		{"scoped host", "https://api.example.com/v1/user", false, map[string]string{"X-Custom": "all", "X-Shared": "custom", "X-Key": "api"}},
+		{"glob host", "https://img.cdn.example.com/logo.png", false, map[string]string{"X-Custom": "all", "X-Shared": "cdn"}},
+		{"glob parent", "https://cdn.example.com/logo.png", false, map[string]string{"X-Custom": "all", "X-Shared": "custom"}},
+	}
+
+	for _, tt := range tests {
+		headers := requestHeaders(tt.url, tt.first, custom, rules)
+		if len(headers) != len(tt.expected) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.expected, headers)
+			continue
+		}
+		for name, value := range tt.expected {
+			if headers[name] != value {
+				t.Errorf("%s: expected %s to be %q, got %q", tt.name, name, value, headers[name])
+			}
+		}
+	}
+}
+
+func TestMergeHeadersReplacesCaseInsensitively(t *testing.T) {
+	existing := http.Header{
+		"Authorization": {"Basic old"},
+		"Accept":        {"text/html"},
+	}
+
+	entries := mergeHeaders(existing, map[string]string{"authorization": "Bearer new"})
+
+	values := make(map[string][]string)
+	for _, entry := range entries {
+		values[entry.Name] = append(values[entry.Name], entry.Value)
+	}
+
+	if len(values["Authorization"]) != 0 {
+		t.Errorf("Expected existing Authorization header to be replaced, got %v", values["Authorization"])
+	}
+	if len(values["authorization"]) != 1 || values["authorization"][0] != "Bearer new" {
+		t.Errorf("Expected added authorization header, got %v", values["authorization"])
+	}
+	if len(values["Accept"]) != 1 || values["Accept"][0] != "text/html" {
+		t.Errorf("Expected Accept header to be kept, got %v", values["Accept"])
+	}
+}
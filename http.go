@@ -1,7 +1,12 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"strconv"
@@ -59,6 +64,156 @@ func loggingMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+const queueRetryAfterSeconds = 5
+
+// acquireWorker waits for a free worker in the request queue. If none becomes
+// available it writes a 503 response and returns false, and if the client
+// disconnects first it returns false without a response.
+func acquireWorker(w http.ResponseWriter, r *http.Request) (func(), bool) {
+	if requestQueue == nil {
+		return func() {}, true
+	}
+
+	release, err := requestQueue.Acquire(r.Context())
+	if err != nil {
+		metrics.FailedRequests.Add(1)
+
+		// The client went away while waiting, there's nobody to answer
+		if errors.Is(err, context.Canceled) {
+			return nil, false
+		}
+
+		metrics.RejectedRequests.Add(1)
+		w.Header().Set("Retry-After", strconv.Itoa(queueRetryAfterSeconds))
+		http.Error(w, fmt.Sprintf("Server busy: %v", err), http.StatusServiceUnavailable)
+		return nil, false
+	}
+
+	return release, true
+}
+
+// queueToolCalls runs MCP tool calls made over HTTP through the request
+// queue, so they share the worker limit with the other endpoints
+func queueToolCalls(next mcp.MethodHandler) mcp.MethodHandler {
+	return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		if method != "tools/call" || requestQueue == nil {
+			return next(ctx, method, req)
+		}
+
+		release, err := requestQueue.Acquire(ctx)
+		if err != nil {
+			if !errors.Is(err, context.Canceled) {
+				metrics.RejectedRequests.Add(1)
+			}
+			return nil, fmt.Errorf("server busy: %w", err)
+		}
+		defer release()
+
+		return next(ctx, method, req)
+	}
+}
+
+// maxRequestBodySize limits the JSON body accepted by POST requests
+const maxRequestBodySize = 1 << 20
+
+// parseRequestBody reads the actions and routes from the JSON body of a POST
+// request, either an actions array or an object with "actions" and "routes"
+// arrays and no other fields
+func parseRequestBody(r *http.Request) ([]Action, []Route, error) {
+	if r.Method != http.MethodPost {
+		return nil, nil, nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxRequestBodySize+1))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read request body: %v", err)
+	}
+	if len(body) > maxRequestBodySize {
+		return nil, nil, fmt.Errorf("request body exceeds %d bytes", maxRequestBodySize)
+	}
+
+	// A bare array only holds actions
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 || body[0] != '{' {
+		actions, err := ParseActions(body)
+		if err != nil {
+			return nil, nil, err
+		}
+		return actions, nil, nil
+	}
+
+	var request struct {
+		Actions json.RawMessage `json:"actions"`
+		Routes  json.RawMessage `json:"routes"`
+	}
+	if err := decodeStrictJSON(body, &request); err != nil {
+		return nil, nil, fmt.Errorf("invalid JSON: %v", err)
+	}
+
+	actions, err := ParseActions(request.Actions)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	routes, err := ParseRoutes(request.Routes, "")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return actions, routes, nil
+}
+
+// applyQueryOverrides sets the optional query parameters shared by every
+// endpoint that override server defaults when present
+func applyQueryOverrides(config *RequestConfig, r *http.Request) error {
+	query := r.URL.Query()
+
+	if userAgent := query.Get("user_agent"); userAgent != "" {
+		config.UserAgent = userAgent
+	}
+	if acceptLanguage := query.Get("accept_language"); acceptLanguage != "" {
+		config.AcceptLanguage = acceptLanguage
+	}
+	if injectCSS := query.Get("inject_css"); injectCSS != "" {
+		config.InjectCSS = injectCSS
+	}
+	if injectJS := query.Get("inject_js"); injectJS != "" {
+		config.InjectJS = injectJS
+	}
+	if injectJSAfterLoad := query.Get("inject_js_after_load"); injectJSAfterLoad != "" {
+		config.InjectJSAfterLoad = injectJSAfterLoad
+	}
+	config.HideSelectors = query["hide_selectors"]
+
+	if query.Has("block_domains") {
+		blockDomains, err := ParseDomainWhitelist(query.Get("block_domains"))
+		if err != nil {
+			return fmt.Errorf("invalid block_domains: %v", err)
+		}
+		config.BlockDomains = blockDomains
+	}
+	if query.Has("block_resource_types") {
+		blockResourceTypes, err := ParseResourceTypes(query.Get("block_resource_types"))
+		if err != nil {
+			return fmt.Errorf("invalid block_resource_types: %v", err)
+		}
+		config.BlockResourceTypes = blockResourceTypes
+	}
+	if adblock := query.Get("adblock"); adblock != "" {
+		enabled, err := strconv.ParseBool(adblock)
+		if err != nil {
+			return fmt.Errorf("invalid adblock: %v", err)
+		}
+		if !enabled {
+			config.AdblockList = nil
+		} else if globalAdblockList == nil {
+			return fmt.Errorf("adblock requires the server to be started with --adblock-list")
+		}
+	}
+
+	return nil
+}
+
 func handleHTML(w http.ResponseWriter, r *http.Request) {
 	if r.URL.Path != "/html" {
 		http.NotFound(w, r)
@@ -76,30 +231,39 @@ func handleHTML(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	viewportParam := r.URL.Query().Get("viewport")
-	timeoutParam := r.URL.Query().Get("timeout")
-	waitParam := r.URL.Query().Get("wait")
-	domainsParam := r.URL.Query().Get("domains")
-	fullHeightParam := r.URL.Query().Get("full_height")
-	colorSchemeParam := r.URL.Query().Get("color_scheme")
-	fullHeight := globalFullHeight
-	if fullHeightParam != "" {
-		parsed, err := strconv.ParseBool(fullHeightParam)
-		if err != nil {
-			metrics.FailedRequests.Add(1)
-			http.Error(w, fmt.Sprintf("Invalid full_height parameter: %v", err), http.StatusBadRequest)
-			return
-		}
-		fullHeight = parsed
+	config, err := parseRequestConfig(r.URL.Query())
+	if err != nil {
+		metrics.FailedRequests.Add(1)
+		http.Error(w, fmt.Sprintf("Invalid parameters: %v", err), http.StatusBadRequest)
+		return
 	}
 
-	config, err := parseRequestConfig(viewportParam, "", timeoutParam, waitParam, domainsParam, colorSchemeParam, fullHeight)
+	config.FullHeight, err = parseFullHeightParam(r.URL.Query().Get("full_height"))
 	if err != nil {
+		metrics.FailedRequests.Add(1)
+		http.Error(w, fmt.Sprintf("Invalid full_height parameter: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if err := applyQueryOverrides(config, r); err != nil {
 		metrics.FailedRequests.Add(1)
 		http.Error(w, fmt.Sprintf("Invalid parameters: %v", err), http.StatusBadRequest)
 		return
 	}
 
+	config.Actions, config.Routes, err = parseRequestBody(r)
+	if err != nil {
+		metrics.FailedRequests.Add(1)
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	release, ok := acquireWorker(w, r)
+	if !ok {
+		return
+	}
+	defer release()
+
 	config.CaptureHTML = true
 	response, err := executeBrowserRequest(url, "", config)
 	duration := time.Since(start)
@@ -119,8 +283,8 @@ func handleHTML(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func handleScreenshot(w http.ResponseWriter, r *http.Request) {
-	if r.URL.Path != "/" {
+func handleText(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/text" {
 		http.NotFound(w, r)
 		return
 	}
@@ -136,31 +300,341 @@ func handleScreenshot(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	viewportParam := r.URL.Query().Get("viewport")
-	resizeParam := r.URL.Query().Get("resize")
-	timeoutParam := r.URL.Query().Get("timeout")
-	waitParam := r.URL.Query().Get("wait")
-	domainsParam := r.URL.Query().Get("domains")
-	fullHeightParam := r.URL.Query().Get("full_height")
-	colorSchemeParam := r.URL.Query().Get("color_scheme")
-	fullHeight := globalFullHeight
-	if fullHeightParam != "" {
-		parsed, err := strconv.ParseBool(fullHeightParam)
+	formatParam := r.URL.Query().Get("format")
+	mainContentParam := r.URL.Query().Get("main_content")
+	maxCharsParam := r.URL.Query().Get("max_chars")
+
+	config, err := parseRequestConfig(r.URL.Query())
+	if err != nil {
+		metrics.FailedRequests.Add(1)
+		http.Error(w, fmt.Sprintf("Invalid parameters: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	textOptions := &TextOptions{}
+	textOptions.Format, err = normalizeTextFormat(formatParam)
+	if err != nil {
+		metrics.FailedRequests.Add(1)
+		http.Error(w, fmt.Sprintf("Invalid format parameter: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if mainContentParam != "" {
+		textOptions.MainContent, err = strconv.ParseBool(mainContentParam)
 		if err != nil {
 			metrics.FailedRequests.Add(1)
-			http.Error(w, fmt.Sprintf("Invalid full_height parameter: %v", err), http.StatusBadRequest)
+			http.Error(w, fmt.Sprintf("Invalid main_content parameter: %v", err), http.StatusBadRequest)
 			return
 		}
-		fullHeight = parsed
 	}
 
-	config, err := parseRequestConfig(viewportParam, resizeParam, timeoutParam, waitParam, domainsParam, colorSchemeParam, fullHeight)
+	textOptions.MaxChars, err = ParseMaxChars(maxCharsParam)
+	if err != nil {
+		metrics.FailedRequests.Add(1)
+		http.Error(w, fmt.Sprintf("Invalid max_chars parameter: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if err := applyQueryOverrides(config, r); err != nil {
+		metrics.FailedRequests.Add(1)
+		http.Error(w, fmt.Sprintf("Invalid parameters: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	config.Actions, config.Routes, err = parseRequestBody(r)
+	if err != nil {
+		metrics.FailedRequests.Add(1)
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	release, ok := acquireWorker(w, r)
+	if !ok {
+		return
+	}
+	defer release()
+
+	config.CaptureText = true
+	config.Text = textOptions
+	response, err := executeBrowserRequest(url, "", config)
+	duration := time.Since(start)
+
+	metrics.TotalDuration.Add(uint64(duration.Nanoseconds()))
+	if err != nil {
+		metrics.FailedRequests.Add(1)
+		http.Error(w, fmt.Sprintf("Error extracting text: %v", err), http.StatusInternalServerError)
+		return
+	} else {
+		metrics.SuccessRequests.Add(1)
+	}
+
+	if textOptions.Format == TextFormatPlain {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	} else {
+		w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+	}
+	if response.TextTruncated {
+		w.Header().Set("X-Text-Truncated", "true")
+	}
+	if response.Text != nil {
+		w.Write([]byte(*response.Text))
+	}
+}
+
+func handleMeta(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/meta" {
+		http.NotFound(w, r)
+		return
+	}
+
+	start := time.Now()
+
+	metrics.TotalRequests.Add(1)
+
+	url := r.URL.Query().Get("url")
+	if url == "" {
+		metrics.FailedRequests.Add(1)
+		http.Error(w, "Missing url parameter", http.StatusBadRequest)
+		return
+	}
+
+	config, err := parseRequestConfig(r.URL.Query())
 	if err != nil {
 		metrics.FailedRequests.Add(1)
 		http.Error(w, fmt.Sprintf("Invalid parameters: %v", err), http.StatusBadRequest)
 		return
 	}
 
+	if err := applyQueryOverrides(config, r); err != nil {
+		metrics.FailedRequests.Add(1)
+		http.Error(w, fmt.Sprintf("Invalid parameters: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	config.Actions, config.Routes, err = parseRequestBody(r)
+	if err != nil {
+		metrics.FailedRequests.Add(1)
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	release, ok := acquireWorker(w, r)
+	if !ok {
+		return
+	}
+	defer release()
+
+	config.CaptureMetadata = true
+	response, err := executeBrowserRequest(url, "", config)
+	duration := time.Since(start)
+
+	metrics.TotalDuration.Add(uint64(duration.Nanoseconds()))
+	if err != nil {
+		metrics.FailedRequests.Add(1)
+		http.Error(w, fmt.Sprintf("Error extracting metadata: %v", err), http.StatusInternalServerError)
+		return
+	} else {
+		metrics.SuccessRequests.Add(1)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response.Metadata)
+}
+
+// parseBoolParam parses an optional boolean query parameter, false when empty
+func parseBoolParam(value string) (bool, error) {
+	if value == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(value)
+}
+
+// parseFullHeightParam parses the full_height query parameter, falling back
+// to --full-height when empty
+func parseFullHeightParam(value string) (bool, error) {
+	if value == "" {
+		return globalFullHeight, nil
+	}
+	return strconv.ParseBool(value)
+}
+
+func handlePDF(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/pdf" {
+		http.NotFound(w, r)
+		return
+	}
+
+	start := time.Now()
+
+	metrics.TotalRequests.Add(1)
+
+	url := r.URL.Query().Get("url")
+	if url == "" {
+		metrics.FailedRequests.Add(1)
+		http.Error(w, "Missing url parameter", http.StatusBadRequest)
+		return
+	}
+
+	config, err := parseRequestConfig(r.URL.Query())
+	if err != nil {
+		metrics.FailedRequests.Add(1)
+		http.Error(w, fmt.Sprintf("Invalid parameters: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if err := applyQueryOverrides(config, r); err != nil {
+		metrics.FailedRequests.Add(1)
+		http.Error(w, fmt.Sprintf("Invalid parameters: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	config.Actions, config.Routes, err = parseRequestBody(r)
+	if err != nil {
+		metrics.FailedRequests.Add(1)
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	landscape, err := parseBoolParam(r.URL.Query().Get("landscape"))
+	if err != nil {
+		metrics.FailedRequests.Add(1)
+		http.Error(w, fmt.Sprintf("Invalid landscape parameter: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	printBackground, err := parseBoolParam(r.URL.Query().Get("print_background"))
+	if err != nil {
+		metrics.FailedRequests.Add(1)
+		http.Error(w, fmt.Sprintf("Invalid print_background parameter: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	config.PDF, err = parsePDFOptions(
+		r.URL.Query().Get("paper"),
+		r.URL.Query().Get("margin"),
+		r.URL.Query().Get("page_ranges"),
+		r.URL.Query().Get("header_template"),
+		r.URL.Query().Get("footer_template"),
+		landscape,
+		printBackground,
+	)
+	if err != nil {
+		metrics.FailedRequests.Add(1)
+		http.Error(w, fmt.Sprintf("Invalid PDF parameters: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	release, ok := acquireWorker(w, r)
+	if !ok {
+		return
+	}
+	defer release()
+
+	config.CapturePDF = true
+	response, err := executeBrowserRequest(url, "", config)
+	duration := time.Since(start)
+
+	metrics.TotalDuration.Add(uint64(duration.Nanoseconds()))
+	if err != nil {
+		metrics.FailedRequests.Add(1)
+		http.Error(w, fmt.Sprintf("Error processing PDF: %v", err), http.StatusInternalServerError)
+		return
+	} else {
+		metrics.SuccessRequests.Add(1)
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Write(response.PDF)
+}
+
+func handleScreenshot(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	start := time.Now()
+
+	metrics.TotalRequests.Add(1)
+
+	url := r.URL.Query().Get("url")
+	if url == "" {
+		metrics.FailedRequests.Add(1)
+		http.Error(w, "Missing url parameter", http.StatusBadRequest)
+		return
+	}
+
+	config, err := parseRequestConfig(r.URL.Query())
+	if err != nil {
+		metrics.FailedRequests.Add(1)
+		http.Error(w, fmt.Sprintf("Invalid parameters: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	config.FullHeight, err = parseFullHeightParam(r.URL.Query().Get("full_height"))
+	if err != nil {
+		metrics.FailedRequests.Add(1)
+		http.Error(w, fmt.Sprintf("Invalid full_height parameter: %v", err), http.StatusBadRequest)
+		return
+	}
+	config.ResizeParam = r.URL.Query().Get("resize")
+
+	if err := applyQueryOverrides(config, r); err != nil {
+		metrics.FailedRequests.Add(1)
+		http.Error(w, fmt.Sprintf("Invalid parameters: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	config.Actions, config.Routes, err = parseRequestBody(r)
+	if err != nil {
+		metrics.FailedRequests.Add(1)
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	config.Selector = r.URL.Query().Get("selector")
+	config.SelectorPadding, err = parsePaddingString(r.URL.Query().Get("padding"))
+	if err != nil {
+		metrics.FailedRequests.Add(1)
+		http.Error(w, fmt.Sprintf("Invalid padding parameter: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	config.Clip, err = ParseClipString(r.URL.Query().Get("clip"))
+	if err != nil {
+		metrics.FailedRequests.Add(1)
+		http.Error(w, fmt.Sprintf("Invalid clip parameter: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	config.Format, err = normalizeImageFormat(r.URL.Query().Get("format"))
+	if err != nil {
+		metrics.FailedRequests.Add(1)
+		http.Error(w, fmt.Sprintf("Invalid format parameter: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	config.Quality, err = ParseQualityString(r.URL.Query().Get("quality"))
+	if err != nil {
+		metrics.FailedRequests.Add(1)
+		http.Error(w, fmt.Sprintf("Invalid quality parameter: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	config.MaskSelectors = r.URL.Query()["mask_selectors"]
+	config.MaskStyle, err = normalizeMaskStyle(r.URL.Query().Get("mask_style"))
+	if err != nil {
+		metrics.FailedRequests.Add(1)
+		http.Error(w, fmt.Sprintf("Invalid mask_style parameter: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	release, ok := acquireWorker(w, r)
+	if !ok {
+		return
+	}
+	defer release()
+
 	config.CaptureScreenshot = true
 	response, err := executeBrowserRequest(url, "", config)
 	duration := time.Since(start)
@@ -179,13 +653,22 @@ func handleScreenshot(w http.ResponseWriter, r *http.Request) {
 }
 
 func StartHTTPServer(listen string, debug bool, enableMCP bool) {
+	initBrowserPools()
+	if globalMaxConcurrency > 0 {
+		requestQueue = NewRequestQueue(globalMaxConcurrency, globalMaxQueue, time.Duration(globalQueueTimeout)*time.Second)
+	}
+
 	mux := http.NewServeMux()
 	mux.HandleFunc("/", handleScreenshot)
 	mux.HandleFunc("/html", handleHTML)
+	mux.HandleFunc("/text", handleText)
+	mux.HandleFunc("/meta", handleMeta)
+	mux.HandleFunc("/pdf", handlePDF)
 	mux.Handle("/metrics", &metrics)
 
 	if enableMCP {
 		server := newMCPServer()
+		server.AddReceivingMiddleware(queueToolCalls)
 		handler := mcp.NewStreamableHTTPHandler(func(_ *http.Request) *mcp.Server {
 			return server
 		}, nil)
@@ -198,10 +681,31 @@ func StartHTTPServer(listen string, debug bool, enableMCP bool) {
 	fmt.Printf("Starting HTTP server on %s\n", listen)
 	fmt.Printf("Screenshot: http://%s/?url=https://leafo.net&viewport=1920x1080&resize=100x200&timeout=30&domains=example.com,*.cdn.com\n", listen)
 	fmt.Printf("HTML: http://%s/html?url=https://leafo.net&viewport=1920x1080&timeout=30&domains=example.com,*.cdn.com\n", listen)
+	fmt.Printf("Text: http://%s/text?url=https://leafo.net&format=markdown&main_content=true&max_chars=20000\n", listen)
+	fmt.Printf("Metadata: http://%s/meta?url=https://leafo.net\n", listen)
+	fmt.Printf("PDF: http://%s/pdf?url=https://leafo.net&paper=a4&margin=1cm&print_background=true\n", listen)
 	if len(globalCustomHeaders) > 0 {
 		fmt.Printf("Custom headers will be applied to all requests: %+v\n", globalCustomHeaders)
 	}
+	if globalAdblockList != nil {
+		fmt.Printf("Adblock list: %s (%d domains)\n", globalAdblockList.Path, globalAdblockList.Len())
+	}
+	if globalNetworkPolicy == nil {
+		fmt.Println("Private network blocking disabled - requests may reach internal addresses")
+	} else if len(globalNetworkPolicy.Allow) > 0 {
+		fmt.Printf("Private network blocking enabled, allowing: %s\n", strings.Join(globalNetworkPolicy.Allow, ", "))
+	}
+	if len(globalHeaderRules) > 0 {
+		fmt.Printf("Header rules will be applied to matching hosts: %d rule(s)\n", len(globalHeaderRules))
+	}
 	fmt.Printf("Metrics: http://%s/metrics\n", listen)
+	if globalProxy != nil {
+		fmt.Printf("Requests will go through proxy %s\n", globalProxy)
+	}
+	fmt.Printf("Browser pool size: %d\n", globalPoolSize)
+	if requestQueue != nil {
+		fmt.Printf("Max concurrency: %d, max queue depth: %d, queue timeout: %ds\n", globalMaxConcurrency, globalMaxQueue, globalQueueTimeout)
+	}
 	if enableMCP {
 		fmt.Printf("MCP (streamable): http://%s/mcp\n", listen)
 	}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// injectBeforeLoad registers the InjectJS script to run in every new
+// document before the page's own scripts. Must be called before navigation.
+func injectBeforeLoad(page *rod.Page, config *RequestConfig) error {
+	if config.InjectJS == "" {
+		return nil
+	}
+
+	if _, err := page.EvalOnNewDocument(config.InjectJS); err != nil {
+		return fmt.Errorf("failed to inject script: %w", err)
+	}
+
+	return nil
+}
+
+// injectAfterLoad adds the InjectCSS stylesheet and runs the
+// InjectJSAfterLoad script once the page has loaded
+func injectAfterLoad(page *rod.Page, config *RequestConfig) error {
+	if config.InjectCSS != "" {
+		if err := page.AddStyleTag("", config.InjectCSS); err != nil {
+			return fmt.Errorf("failed to inject CSS: %w", err)
+		}
+	}
+
+	if config.InjectJSAfterLoad != "" {
+		result, err := proto.RuntimeEvaluate{
+			Expression:   config.InjectJSAfterLoad,
+			AwaitPromise: true,
+		}.Call(page)
+		if err != nil {
+			return fmt.Errorf("failed to run injected script: %w", err)
+		}
+		if result.ExceptionDetails != nil {
+			return fmt.Errorf("injected script threw: %s", exceptionMessage(result.ExceptionDetails))
+		}
+	}
+
+	return nil
+}
+
+// exceptionMessage returns the most descriptive message for a JS exception
+func exceptionMessage(details *proto.RuntimeExceptionDetails) string {
+	if details.Exception != nil && details.Exception.Description != "" {
+		return details.Exception.Description
+	}
+	return details.Text
+}
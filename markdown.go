@@ -0,0 +1,310 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-rod/rod"
+)
+
+// Formats accepted for text extraction
+const (
+	TextFormatMarkdown = "markdown"
+	TextFormatPlain    = "text"
+)
+
+// TextOptions controls how the rendered page is converted to readable text
+type TextOptions struct {
+	Format      string // markdown (default) or text
+	MainContent bool   // Keep only the main content, dropping navigation and other boilerplate
+	MaxChars    int    // Truncate the result to this many characters (0 for no limit)
+}
+
+// normalizeTextFormat validates a text format, defaulting to markdown
+func normalizeTextFormat(format string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "", "md", TextFormatMarkdown:
+		return TextFormatMarkdown, nil
+	case "txt", "plain", TextFormatPlain:
+		return TextFormatPlain, nil
+	default:
+		return "", fmt.Errorf("invalid text format %q, must be markdown or text", format)
+	}
+}
+
+// ParseMaxChars parses a character budget, 0 or empty for no limit
+func ParseMaxChars(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	maxChars, err := strconv.Atoi(value)
+	if err != nil || maxChars < 0 {
+		return 0, fmt.Errorf("max chars must be a non-negative integer")
+	}
+
+	return maxChars, nil
+}
+
+// truncateText cuts text to at most maxChars characters plus a marker saying
+// how much was removed, preferring to cut at a paragraph or line break
+func truncateText(text string, maxChars int) (string, bool) {
+	total := utf8.RuneCountInString(text)
+	if maxChars <= 0 || total <= maxChars {
+		return text, false
+	}
+
+	runes := []rune(text)
+	cut := string(runes[:maxChars])
+
+	// Only back up to a break if that keeps most of the budget
+	for _, separator := range []string{"\n\n", "\n"} {
+		if index := strings.LastIndex(cut, separator); index > len(cut)/2 {
+			cut = cut[:index]
+			break
+		}
+	}
+
+	cut = strings.TrimRight(cut, " \t\n")
+	removed := total - utf8.RuneCountInString(cut)
+
+	return fmt.Sprintf("%s\n\n[... truncated %d characters ...]", cut, removed), true
+}
+
+// extractText converts the rendered page to Markdown or plain text, reporting
+// whether it was truncated to fit MaxChars
+func extractText(page *rod.Page, options *TextOptions) (string, bool, error) {
+	if options == nil {
+		options = &TextOptions{}
+	}
+
+	result, err := page.Eval(domToMarkdownJS, options.MainContent, options.Format == TextFormatPlain)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to extract text: %w", err)
+	}
+
+	text, truncated := truncateText(result.Value.Str(), options.MaxChars)
+	return text, truncated, nil
+}
+
+// domToMarkdownJS walks the rendered DOM and returns it as Markdown, or as
+// plain text when plain is set. With mainContent it first picks the element
+// most likely to hold the article, scoring paragraph text like Readability.
+const domToMarkdownJS = `(mainContent, plain) => {
+	const INDENT = "\u0001"
+	const CODE = "\u0002"
+	const codeBlocks = []
+
+	const SKIP = new Set(["SCRIPT", "STYLE", "NOSCRIPT", "TEMPLATE", "SVG", "CANVAS", "IFRAME", "OBJECT", "EMBED", "HEAD", "INPUT", "SELECT", "TEXTAREA", "BUTTON"])
+	const BOILERPLATE = new Set(["NAV", "ASIDE", "FORM"])
+	const BLOCK = new Set(["ADDRESS", "ARTICLE", "DD", "DETAILS", "DIV", "DL", "DT", "FIELDSET", "FIGCAPTION", "FIGURE", "FOOTER", "HEADER", "MAIN", "NAV", "P", "SECTION", "SUMMARY", "ASIDE", "FORM", "CENTER"])
+
+	const hidden = (element) => {
+		if (element.hidden || element.getAttribute("aria-hidden") === "true") {
+			return true
+		}
+		const style = window.getComputedStyle(element)
+		return style.display === "none" || style.visibility === "hidden"
+	}
+
+	// A header or footer is only boilerplate at the page level, inside the
+	// chosen article it holds the title or byline
+	const isBoilerplate = (element) => {
+		if (element.tagName === "HEADER" || element.tagName === "FOOTER") {
+			const section = element.parentElement && element.parentElement.closest("article, main, section, [role=main]")
+			return !section || !root.contains(section)
+		}
+		if (BOILERPLATE.has(element.tagName)) {
+			return true
+		}
+		const role = element.getAttribute("role")
+		return role === "navigation" || role === "banner" || role === "contentinfo" || role === "complementary"
+	}
+
+	const linkDensity = (element) => {
+		const length = element.textContent.length
+		if (!length) {
+			return 0
+		}
+		let links = 0
+		for (const link of element.querySelectorAll("a")) {
+			links += link.textContent.length
+		}
+		return links / length
+	}
+
+	const findMainContent = () => {
+		const landmarks = [...document.querySelectorAll("article, main, [role=main]")]
+			.filter((element) => !hidden(element))
+		if (landmarks.length) {
+			return landmarks.reduce((best, element) =>
+				element.textContent.length > best.textContent.length ? element : best)
+		}
+
+		const scores = new Map()
+		for (const paragraph of document.querySelectorAll("p, pre, td")) {
+			const length = paragraph.textContent.trim().length
+			if (length < 25) {
+				continue
+			}
+			const score = 1 + paragraph.textContent.split(",").length + Math.min(Math.floor(length / 100), 3)
+			const parent = paragraph.parentElement
+			const grandparent = parent && parent.parentElement
+			if (parent) {
+				scores.set(parent, (scores.get(parent) || 0) + score)
+			}
+			if (grandparent) {
+				scores.set(grandparent, (scores.get(grandparent) || 0) + score / 2)
+			}
+		}
+
+		let best = document.body
+		let bestScore = 0
+		for (const [element, score] of scores) {
+			const adjusted = score * (1 - linkDensity(element))
+			if (adjusted > bestScore) {
+				best = element
+				bestScore = adjusted
+			}
+		}
+		return best
+	}
+
+	const root = mainContent ? findMainContent() : document.body
+	if (!root) {
+		return ""
+	}
+
+	const escapeCell = (text) => text.replace(/\|/g, "\\|").replace(/\s+/g, " ").trim()
+
+	const inner = (element) => [...element.childNodes].map(convert).join("")
+
+	const block = (text) => "\n\n" + text.trim() + "\n\n"
+
+	// wrap puts markup around the text of an inline element, keeping the
+	// whitespace at its edges outside so words stay separated
+	const wrap = (content, before, after) => {
+		const text = content.trim()
+		if (!text || plain) {
+			return content
+		}
+		return content.match(/^\s*/)[0] + before + text + after + content.match(/\s*$/)[0]
+	}
+
+	const list = (element) => {
+		const ordered = element.tagName === "OL"
+		let number = parseInt(element.getAttribute("start") || "1", 10)
+		const items = []
+		for (const child of element.children) {
+			if (child.tagName !== "LI" || hidden(child)) {
+				continue
+			}
+			const marker = ordered ? (number++) + ". " : "- "
+			const lines = inner(child).trim().split("\n").filter((line) => line.trim() !== "")
+			if (!lines.length) {
+				continue
+			}
+			const indent = INDENT.repeat(marker.length)
+			items.push(marker + lines[0] + lines.slice(1).map((line) => "\n" + indent + line).join(""))
+		}
+		return items.length ? "\n\n" + items.join("\n") + "\n\n" : ""
+	}
+
+	const table = (element) => {
+		const rows = [...element.rows].map((row) =>
+			[...row.cells].map((cell) => escapeCell(inner(cell))))
+		if (!rows.length) {
+			return ""
+		}
+		if (plain) {
+			return "\n\n" + rows.map((cells) => cells.join("\t")).join("\n") + "\n\n"
+		}
+		const columns = Math.max(...rows.map((cells) => cells.length))
+		const line = (cells) => "| " + [...cells, ...Array(columns - cells.length).fill("")].join(" | ") + " |"
+		const separator = "| " + Array(columns).fill("---").join(" | ") + " |"
+		return "\n\n" + [line(rows[0]), separator, ...rows.slice(1).map(line)].join("\n") + "\n\n"
+	}
+
+	const convert = (node) => {
+		if (node.nodeType === Node.TEXT_NODE) {
+			return node.textContent.replace(/[\u0001\u0002]/g, "").replace(/\s+/g, " ")
+		}
+		if (node.nodeType !== Node.ELEMENT_NODE) {
+			return ""
+		}
+
+		const element = node
+		const tag = element.tagName.toUpperCase()
+		if (SKIP.has(tag) || hidden(element) || (mainContent && isBoilerplate(element))) {
+			return ""
+		}
+
+		switch (tag) {
+		case "H1": case "H2": case "H3": case "H4": case "H5": case "H6": {
+			const text = inner(element).trim()
+			if (!text) {
+				return ""
+			}
+			return block(plain ? text : "#".repeat(Number(tag[1])) + " " + text)
+		}
+		case "BR":
+			return "\n"
+		case "HR":
+			return plain ? "\n\n" : "\n\n---\n\n"
+		case "A": {
+			const href = element.href
+			if (!href || href.startsWith("javascript:")) {
+				return inner(element)
+			}
+			return wrap(inner(element), "[", "](" + href + ")")
+		}
+		case "IMG": {
+			const alt = (element.getAttribute("alt") || "").trim()
+			if (!alt) {
+				return ""
+			}
+			return plain ? alt : "![" + alt + "](" + element.src + ")"
+		}
+		case "STRONG": case "B":
+			return wrap(inner(element), "**", "**")
+		case "EM": case "I":
+			return wrap(inner(element), "*", "*")
+		case "CODE": {
+			const text = element.textContent
+			return plain ? text : "` + "`" + `" + text + "` + "`" + `"
+		}
+		case "PRE": {
+			codeBlocks.push(plain ? element.textContent : "` + "```" + `\n" + element.textContent.replace(/\n$/, "") + "\n` + "```" + `")
+			return "\n\n" + CODE + (codeBlocks.length - 1) + CODE + "\n\n"
+		}
+		case "BLOCKQUOTE": {
+			const text = inner(element).trim()
+			if (plain) {
+				return block(text)
+			}
+			return block(text.split("\n").map((line) => "> " + line).join("\n"))
+		}
+		case "UL": case "OL":
+			return list(element)
+		case "TABLE":
+			return table(element)
+		case "LI":
+			return block(inner(element))
+		default:
+			return BLOCK.has(tag) ? block(inner(element)) : inner(element)
+		}
+	}
+
+	let text = convert(root)
+		.split("\n")
+		.map((line) => line.replace(/[ \t]+/g, " ").trim())
+		.join("\n")
+		.replace(/\n{3,}/g, "\n\n")
+		.replace(/\u0001/g, " ")
+		.trim()
+
+	text = text.replace(/\u0002(\d+)\u0002/g, (match, index) => codeBlocks[Number(index)])
+	return text
+}`
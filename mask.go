@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// Styles accepted by mask_style
+const (
+	MaskStyleSolid = "solid"
+	MaskStyleBlur  = "blur"
+)
+
+// maskBlurSigma is the gaussian blur radius in pixels for blurred masks
+const maskBlurSigma = 20
+
+// MaskRect is an area of the captured image to mask, in image pixels
+type MaskRect struct {
+	X      int
+	Y      int
+	Width  int
+	Height int
+}
+
+type maskedElements struct {
+	Boxes   []elementBox `json:"boxes"`
+	ScrollX float64      `json:"scrollX"`
+	ScrollY float64      `json:"scrollY"`
+}
+
+// normalizeMaskStyle validates a mask style, defaulting to solid
+func normalizeMaskStyle(style string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(style)) {
+	case "", MaskStyleSolid:
+		return MaskStyleSolid, nil
+	case MaskStyleBlur:
+		return MaskStyleBlur, nil
+	default:
+		return "", fmt.Errorf("invalid mask style %q, must be solid or blur", style)
+	}
+}
+
+// joinSelectors combines selectors into a single CSS selector list, dropping
+// empty entries
+func joinSelectors(selectors []string) string {
+	var nonEmpty []string
+	for _, selector := range selectors {
+		if selector = strings.TrimSpace(selector); selector != "" {
+			nonEmpty = append(nonEmpty, selector)
+		}
+	}
+	return strings.Join(nonEmpty, ", ")
+}
+
+// hideElements adds a stylesheet that hides every element matching selectors
+// while keeping the page layout unchanged
+func hideElements(page *rod.Page, selectors []string) error {
+	selector := joinSelectors(selectors)
+	if selector == "" {
+		return nil
+	}
+
+	if err := page.AddStyleTag("", selector+" { visibility: hidden !important }"); err != nil {
+		return fmt.Errorf("failed to hide selectors %q: %w", selector, err)
+	}
+
+	return nil
+}
+
+// maskRects returns the rectangles of every element matching selectors in the
+// pixels of a screenshot taken of clip, or of the viewport when clip is nil
+func maskRects(page *rod.Page, selectors []string, clip *proto.PageViewport, scale float64) ([]MaskRect, error) {
+	selector := joinSelectors(selectors)
+	if selector == "" {
+		return nil, nil
+	}
+
+	result, err := page.Eval(`(selector) => {
+		const boxes = Array.from(document.querySelectorAll(selector), (element) => {
+			const rect = element.getBoundingClientRect()
+			return {x: rect.left + window.scrollX, y: rect.top + window.scrollY, width: rect.width, height: rect.height}
+		})
+		return {boxes, scrollX: window.scrollX, scrollY: window.scrollY}
+	}`, selector)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find elements to mask for %q: %w", selector, err)
+	}
+
+	var masked maskedElements
+	if err := result.Value.Unmarshal(&masked); err != nil {
+		return nil, fmt.Errorf("failed to find elements to mask for %q: %w", selector, err)
+	}
+
+	originX, originY := masked.ScrollX, masked.ScrollY
+	if clip != nil {
+		originX, originY = clip.X, clip.Y
+	}
+
+	rects := make([]MaskRect, 0, len(masked.Boxes))
+	for _, box := range masked.Boxes {
+		if box.Width <= 0 || box.Height <= 0 {
+			continue
+		}
+
+		// Round outwards so partially covered pixels are masked too
+		left := math.Floor((box.X - originX) * scale)
+		top := math.Floor((box.Y - originY) * scale)
+		right := math.Ceil((box.X + box.Width - originX) * scale)
+		bottom := math.Ceil((box.Y + box.Height - originY) * scale)
+
+		rects = append(rects, MaskRect{
+			X:      int(left),
+			Y:      int(top),
+			Width:  int(right - left),
+			Height: int(bottom - top),
+		})
+	}
+
+	return rects, nil
+}
@@ -9,36 +9,69 @@ import (
 
 // BrowserContextConfig stores browser configuration for a named context
 type BrowserContextConfig struct {
-	Name            string
-	DefaultViewport ViewportConfig
-	DefaultTimeout  int
-	DefaultWait     int
-	DomainWhitelist []string
-	Cookies         []*proto.NetworkCookieParam
-	Headers         map[string]string
-	ColorScheme     string
-	LastRequestID   string
-	RequestHistory  []string // Request IDs in chronological order
-	CreatedAt       time.Time
-	LastUsed        time.Time
-	mutex           sync.RWMutex
+	Name               string
+	DefaultViewport    ViewportConfig
+	DefaultScale       float64
+	DefaultTimeout     int
+	DefaultWait        int
+	DomainWhitelist    []string
+	BlockDomains       []string
+	BlockResourceTypes []string
+	AdblockList        *AdblockList
+	Cookies            []*proto.NetworkCookieParam
+	Headers            map[string]string
+	HeaderRules        []HeaderRule
+	Routes             []Route
+	ColorScheme        string
+	BrowserURL         string
+	Proxy              *Proxy
+	Device             string
+	UserAgent          string
+	AcceptLanguage     string
+	InjectCSS          string
+	InjectJS           string
+	InjectJSAfterLoad  string
+	LastRequestID      string
+	RequestHistory     []string // Request IDs in chronological order
+	CreatedAt          time.Time
+	LastUsed           time.Time
+	mutex              sync.RWMutex
+}
+
+// baseViewportAndScale returns the viewport and scale set by the command line
+// flags, without any device preset
+func baseViewportAndScale() (ViewportConfig, float64) {
+	viewport := ViewportConfig{Width: 1366, Height: 854}
+	if globalViewport != "" {
+		if width, height, err := ParseViewportString(globalViewport); err == nil {
+			viewport = ViewportConfig{Width: width, Height: height}
+		}
+	}
+
+	return viewport, globalScale
 }
 
 func DefaultBrowserContextConfig() *BrowserContextConfig {
 	// Start with default values
-	viewport := ViewportConfig{Width: 1366, Height: 854}
+	viewport, scale := baseViewportAndScale()
 	timeout := 30
 	wait := 0
 	var domainWhitelist []string
 	headers := make(map[string]string)
 
-	// Apply global CLI flags if they were set
-	if globalViewport != "" {
-		if width, height, err := ParseViewportString(globalViewport); err == nil {
-			viewport = ViewportConfig{Width: width, Height: height}
+	// A device preset provides the viewport and scale unless set explicitly
+	if globalDevice != "" {
+		if preset, err := LookupDevice(globalDevice); err == nil {
+			if globalViewport == "" {
+				viewport = ViewportConfig{Width: preset.Width, Height: preset.Height}
+			}
+			if scale == 0 {
+				scale = preset.Scale
+			}
 		}
 	}
 
+	// Apply global CLI flags if they were set
 	if globalTimeout > 0 {
 		timeout = globalTimeout
 	}
@@ -58,16 +91,81 @@ func DefaultBrowserContextConfig() *BrowserContextConfig {
 	}
 
 	return &BrowserContextConfig{
-		Name:            "default",
-		DefaultViewport: viewport,
-		DefaultTimeout:  timeout,
-		DefaultWait:     wait,
-		DomainWhitelist: domainWhitelist,
-		Cookies:         []*proto.NetworkCookieParam{},
-		Headers:         headers,
-		ColorScheme:     globalColorScheme,
-		RequestHistory:  []string{},
+		Name:               "default",
+		DefaultViewport:    viewport,
+		DefaultScale:       scale,
+		DefaultTimeout:     timeout,
+		DefaultWait:        wait,
+		DomainWhitelist:    domainWhitelist,
+		BlockDomains:       globalBlockDomains,
+		BlockResourceTypes: globalBlockResourceTypes,
+		AdblockList:        globalAdblockList,
+		Cookies:            []*proto.NetworkCookieParam{},
+		Headers:            headers,
+		HeaderRules:        globalHeaderRules,
+		ColorScheme:        globalColorScheme,
+		BrowserURL:         globalBrowserURL,
+		Proxy:              globalProxy,
+		Device:             globalDevice,
+		UserAgent:          globalUserAgent,
+		AcceptLanguage:     globalAcceptLanguage,
+		InjectCSS:          globalInjectCSS,
+		InjectJS:           globalInjectJS,
+		InjectJSAfterLoad:  globalInjectJSAfterLoad,
+		RequestHistory:     []string{},
+	}
+}
+
+// requestConfig builds a request from the context's settings and device
+// preset, for a tool to add its own arguments to
+func (c *BrowserContextConfig) requestConfig() (*RequestConfig, error) {
+	requestConfig := &RequestConfig{
+		ViewportWidth:      c.DefaultViewport.Width,
+		ViewportHeight:     c.DefaultViewport.Height,
+		Scale:              c.DefaultScale,
+		TimeoutSeconds:     c.DefaultTimeout,
+		WaitUntil:          WaitUntilLoad,
+		DomainWhitelist:    c.DomainWhitelist,
+		BlockDomains:       c.BlockDomains,
+		BlockResourceTypes: c.BlockResourceTypes,
+		AdblockList:        c.AdblockList,
+		CustomHeaders:      c.Headers,
+		HeaderRules:        c.HeaderRules,
+		Routes:             c.Routes,
+		NetworkPolicy:      globalNetworkPolicy,
+		Cookies:            c.Cookies,
+		ColorScheme:        c.ColorScheme,
+		BrowserURL:         c.BrowserURL,
+		Proxy:              c.Proxy,
+		UserAgent:          c.UserAgent,
+		AcceptLanguage:     c.AcceptLanguage,
+		InjectCSS:          c.InjectCSS,
+		InjectJS:           c.InjectJS,
+		InjectJSAfterLoad:  c.InjectJSAfterLoad,
+		Debug:              globalDebug,
 	}
+
+	if err := c.ApplyDevice(requestConfig); err != nil {
+		return nil, err
+	}
+
+	return requestConfig, nil
+}
+
+// ApplyDevice enables the mobile, touch and user agent emulation of the
+// context's device preset on a request
+func (c *BrowserContextConfig) ApplyDevice(requestConfig *RequestConfig) error {
+	if c.Device == "" {
+		return nil
+	}
+
+	preset, err := LookupDevice(c.Device)
+	if err != nil {
+		return err
+	}
+
+	preset.Apply(requestConfig)
+	return nil
 }
 
 // ViewportConfig represents viewport dimensions
@@ -179,15 +277,29 @@ func (m *ContextConfigManager) ListContexts() map[string]interface{} {
 	result := make(map[string]interface{})
 	for name, context := range m.contexts {
 		result[name] = map[string]interface{}{
-			"created_at":    context.CreatedAt,
-			"last_used":     context.LastUsed,
-			"request_count": len(context.RequestHistory),
-			"viewport":      context.DefaultViewport,
-			"timeout":       context.DefaultTimeout,
-			"wait":          context.DefaultWait,
-			"cookies":       context.Cookies,
-			"headers":       context.Headers,
-			"color_scheme":  context.ColorScheme,
+			"created_at":           context.CreatedAt,
+			"last_used":            context.LastUsed,
+			"request_count":        len(context.RequestHistory),
+			"viewport":             context.DefaultViewport,
+			"scale":                context.DefaultScale,
+			"timeout":              context.DefaultTimeout,
+			"wait":                 context.DefaultWait,
+			"cookies":              context.Cookies,
+			"block_domains":        context.BlockDomains,
+			"block_resource_types": context.BlockResourceTypes,
+			"adblock":              context.AdblockList != nil,
+			"headers":              context.Headers,
+			"header_rules":         context.HeaderRules,
+			"routes":               context.Routes,
+			"color_scheme":         context.ColorScheme,
+			"browser_url":          context.BrowserURL,
+			"proxy":                context.Proxy.String(),
+			"device":               context.Device,
+			"user_agent":           context.UserAgent,
+			"accept_language":      context.AcceptLanguage,
+			"inject_css":           context.InjectCSS,
+			"inject_js":            context.InjectJS,
+			"inject_js_after_load": context.InjectJSAfterLoad,
 		}
 	}
 	return result
@@ -8,16 +8,17 @@ import (
 )
 
 type RequestHistoryEntry struct {
-	ID          string           `json:"id"`
-	ContextName string           `json:"context_name"`
-	URL         string           `json:"url"`
-	InputHTML   string           `json:"input_html,omitempty"` // HTML content for HTML-based requests
-	Timestamp   time.Time        `json:"timestamp"`
-	Duration    time.Duration    `json:"duration_ms"`
-	RequestType string           `json:"request_type"` // screenshot, get_html, screenshot_html
-	Config      *RequestConfig   `json:"config"`
-	Response    *BrowserResponse `json:"response"`
-	Error       string           `json:"error,omitempty"`
+	ID                string           `json:"id"`
+	ContextName       string           `json:"context_name"`
+	URL               string           `json:"url"`
+	InputHTML         string           `json:"input_html,omitempty"` // HTML content for HTML-based requests
+	Timestamp         time.Time        `json:"timestamp"`
+	Duration          time.Duration    `json:"duration_ms"`
+	RequestType       string           `json:"request_type"` // screenshot, get_html, screenshot_html, pdf, pdf_html, evaluate
+	Config            *RequestConfig   `json:"config"`
+	Response          *BrowserResponse `json:"response"`
+	Error             string           `json:"error,omitempty"`
+	WaitForFunctionMs int64            `json:"wait_for_function_ms,omitempty"` // Time spent waiting for wait_for_function
 }
 
 // RequestHistoryManager manages stored browser requests
@@ -51,6 +52,10 @@ func NewRequestHistoryEntry(contextName, url, inputHTML, requestType string, con
 		entry.Error = err.Error()
 	}
 
+	if response != nil {
+		entry.WaitForFunctionMs = response.WaitForFunctionMs
+	}
+
 	return entry
 }
 
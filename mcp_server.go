@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"time"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
@@ -13,6 +14,7 @@ import (
 var (
 	configManager  *ContextConfigManager
 	requestManager *RequestHistoryManager
+	sessionManager *SessionManager
 )
 
 func StartMCPServer() {
@@ -30,6 +32,8 @@ func StartMCPServer() {
 func newMCPServer() *mcp.Server {
 	configManager = NewContextConfigManager()
 	requestManager = NewRequestHistoryManager()
+	sessionManager = NewSessionManager(time.Duration(globalSessionIdleTimeout) * time.Second)
+	initBrowserPools()
 
 	server := mcp.NewServer(&mcp.Implementation{
 		Name:    "sitecap",
@@ -58,23 +62,95 @@ func registerTools(server *mcp.Server) {
 	// Screenshot capture tools
 	mcp.AddTool(server, &mcp.Tool{
 		Name:        "capture_screenshot_from_url",
-		Description: "Capture a screenshot of a webpage by navigating to the specified URL. Returns a base64-encoded PNG image. Supports viewport control, image resizing, and cookie management.",
+		Description: "Capture a screenshot of a webpage by navigating to the specified URL. Returns a base64-encoded PNG, JPEG or WebP image. Supports viewport control, image resizing, and cookie management.",
 	}, handleMCPScreenshot)
 
 	mcp.AddTool(server, &mcp.Tool{
 		Name:        "capture_screenshot_from_html",
-		Description: "Capture a screenshot by rendering arbitrary HTML content in the browser. Useful for generating images from HTML templates or custom content. Returns a base64-encoded PNG image.",
+		Description: "Capture a screenshot by rendering arbitrary HTML content in the browser. Useful for generating images from HTML templates or custom content. Returns a base64-encoded PNG, JPEG or WebP image.",
 	}, handleMCPScreenshotHTML)
 
+	// PDF capture tools
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "capture_pdf_from_url",
+		Description: "Print a webpage to PDF by navigating to the specified URL. Returns the PDF as an embedded resource. Supports paper size, margins, landscape, background graphics, header/footer templates and page ranges.",
+	}, handleMCPPDF)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "capture_pdf_from_html",
+		Description: "Print arbitrary HTML content to PDF. Useful for generating invoices and reports from HTML templates. Returns the PDF as an embedded resource.",
+	}, handleMCPPDFHTML)
+
 	// Content extraction tools
 	mcp.AddTool(server, &mcp.Tool{
 		Name:        "extract_html_content",
 		Description: "Extract the fully rendered HTML content from a webpage after JavaScript execution. Use this to get the final DOM state including dynamically generated content.",
 	}, handleMCPGetHTML)
 
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "evaluate_javascript",
+		Description: "Evaluate a JavaScript expression in a webpage loaded from a URL or HTML content after JavaScript execution, and return the result as JSON. Use this to extract computed values like prices, document.title or JSON embedded in script tags. Exceptions are reported with line and column numbers.",
+	}, handleEvaluateJavaScript)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "get_page_markdown",
+		Description: "Convert a webpage loaded from a URL or HTML content to clean Markdown after JavaScript execution, keeping headings, links, lists, tables and image alt text. Optionally keeps only the main content and truncates to a character budget. Use this to read a page without the cost of raw HTML.",
+	}, handleGetPageMarkdown)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "get_page_metadata",
+		Description: "Read structured metadata from a webpage loaded from a URL or HTML content after JavaScript execution: title, meta description, canonical URL, OpenGraph and Twitter card fields, favicons, JSON-LD blocks, lang and the final URL after redirects. Useful for building link previews.",
+	}, handleGetPageMetadata)
+
 	// Request history tools
 	mcp.AddTool(server, &mcp.Tool{
 		Name:        "get_last_browser_request",
 		Description: "Retrieve details about the most recent browser request made in a specific context. Includes request/response data, cookies, network details, and console logs if requested.",
 	}, handleGetLastRequest)
+
+	// Interactive page session tools
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "open_page",
+		Description: "Open a browser page that stays alive across tool calls, optionally loading a URL. Returns a session_id for navigate, click, type, scroll, evaluate, screenshot_page, get_page_html and close_page. Uses the settings of a named browser context. Idle pages are closed automatically.",
+	}, handleOpenPage)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "navigate",
+		Description: "Load a URL in an open page and wait for it to finish loading.",
+	}, handleNavigate)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "click",
+		Description: "Click the element matching a CSS selector in an open page, waiting for it to appear.",
+	}, handleClick)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "type",
+		Description: "Type text into the element matching a CSS selector, or the focused element, in an open page. Optionally presses Enter afterwards.",
+	}, handleType)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "scroll",
+		Description: "Scroll an open page, either bringing the element matching a CSS selector into view or by a number of pixels.",
+	}, handleScroll)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "evaluate",
+		Description: "Evaluate a JavaScript expression in an open page and return the result as JSON. Promises are awaited.",
+	}, handleEvaluate)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "screenshot_page",
+		Description: "Capture a screenshot of the current state of an open page. Returns a base64-encoded PNG, JPEG or WebP image.",
+	}, handleScreenshotPage)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "get_page_html",
+		Description: "Get the current rendered HTML of an open page.",
+	}, handleGetPageHTML)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "close_page",
+		Description: "Close an open page and dispose of its browser context.",
+	}, handleClosePage)
 }
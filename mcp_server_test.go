@@ -85,6 +85,7 @@ func setupTestServer() *mcp.Server {
 	// Initialize global managers (same as in RunMCPServer)
 	configManager = NewContextConfigManager()
 	requestManager = NewRequestHistoryManager()
+	sessionManager = NewSessionManager(0)
 
 	// Create the MCP server
 	server := mcp.NewServer(&mcp.Implementation{
@@ -166,10 +167,24 @@ func TestMCPServerToolsList(t *testing.T) {
 	expectedTools := map[string]string{
 		"configure_browser_context":    "Configure browser settings (viewport, timeout, cookies, headers) for a named browsing context. Use this to set up the browser environment before capturing screenshots or extracting content.",
 		"list_browser_contexts":        "List all configured browser contexts with their settings. Use this to see available contexts and their configurations.",
-		"capture_screenshot_from_url":  "Capture a screenshot of a webpage by navigating to the specified URL. Returns a base64-encoded PNG image. Supports viewport control, image resizing, and cookie management.",
-		"capture_screenshot_from_html": "Capture a screenshot by rendering arbitrary HTML content in the browser. Useful for generating images from HTML templates or custom content. Returns a base64-encoded PNG image.",
+		"capture_screenshot_from_url":  "Capture a screenshot of a webpage by navigating to the specified URL. Returns a base64-encoded PNG, JPEG or WebP image. Supports viewport control, image resizing, and cookie management.",
+		"capture_screenshot_from_html": "Capture a screenshot by rendering arbitrary HTML content in the browser. Useful for generating images from HTML templates or custom content. Returns a base64-encoded PNG, JPEG or WebP image.",
+		"capture_pdf_from_url":         "Print a webpage to PDF by navigating to the specified URL. Returns the PDF as an embedded resource. Supports paper size, margins, landscape, background graphics, header/footer templates and page ranges.",
+		"capture_pdf_from_html":        "Print arbitrary HTML content to PDF. Useful for generating invoices and reports from HTML templates. Returns the PDF as an embedded resource.",
 		"extract_html_content":         "Extract the fully rendered HTML content from a webpage after JavaScript execution. Use this to get the final DOM state including dynamically generated content.",
+		"evaluate_javascript":          "Evaluate a JavaScript expression in a webpage loaded from a URL or HTML content after JavaScript execution, and return the result as JSON. Use this to extract computed values like prices, document.title or JSON embedded in script tags. Exceptions are reported with line and column numbers.",
+		"get_page_markdown":            "Convert a webpage loaded from a URL or HTML content to clean Markdown after JavaScript execution, keeping headings, links, lists, tables and image alt text. Optionally keeps only the main content and truncates to a character budget. Use this to read a page without the cost of raw HTML.",
+		"get_page_metadata":            "Read structured metadata from a webpage loaded from a URL or HTML content after JavaScript execution: title, meta description, canonical URL, OpenGraph and Twitter card fields, favicons, JSON-LD blocks, lang and the final URL after redirects. Useful for building link previews.",
 		"get_last_browser_request":     "Retrieve details about the most recent browser request made in a specific context. Includes request/response data, cookies, network details, and console logs if requested.",
+		"open_page":                    "Open a browser page that stays alive across tool calls, optionally loading a URL. Returns a session_id for navigate, click, type, scroll, evaluate, screenshot_page, get_page_html and close_page. Uses the settings of a named browser context. Idle pages are closed automatically.",
+		"navigate":                     "Load a URL in an open page and wait for it to finish loading.",
+		"click":                        "Click the element matching a CSS selector in an open page, waiting for it to appear.",
+		"type":                         "Type text into the element matching a CSS selector, or the focused element, in an open page. Optionally presses Enter afterwards.",
+		"scroll":                       "Scroll an open page, either bringing the element matching a CSS selector into view or by a number of pixels.",
+		"evaluate":                     "Evaluate a JavaScript expression in an open page and return the result as JSON. Promises are awaited.",
+		"screenshot_page":              "Capture a screenshot of the current state of an open page. Returns a base64-encoded PNG, JPEG or WebP image.",
+		"get_page_html":                "Get the current rendered HTML of an open page.",
+		"close_page":                   "Close an open page and dispose of its browser context.",
 	}
 
 	if len(toolsResult.Tools) != len(expectedTools) {
@@ -1264,3 +1279,116 @@ func TestMCPServerCLIFlagsAppliedToDefaultContext(t *testing.T) {
 
 	t.Log("All CLI flags were successfully applied to the default context and RequestConfig")
 }
+
+// TestMCPServerSelectorScreenshot tests capturing a single element by CSS selector
+func TestMCPServerSelectorScreenshot(t *testing.T) {
+	htmlContent := `<!DOCTYPE html>
+<html>
+<head>
+    <style>
+        body { margin: 0; padding: 50px; }
+        #card { width: 200px; height: 100px; background: #1976d2; }
+        #empty { width: 0; height: 0; }
+    </style>
+</head>
+<body>
+    <div id="card"></div>
+    <div id="empty"></div>
+</body>
+</html>`
+
+	// Setup MCP server for testing
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	server := setupTestServer()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+		err := server.Run(ctx, serverTransport)
+		if err != nil && err != context.Canceled {
+			t.Errorf("Server run error: %v", err)
+		}
+	}()
+
+	client := mcp.NewClient(&mcp.Implementation{
+		Name:    "test-client",
+		Version: "1.0.0",
+	}, nil)
+
+	session, err := client.Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatalf("Failed to connect client to server: %v", err)
+	}
+	defer session.Close()
+
+	// Capture the card with padding, image should be the element size plus padding on each side
+	result, err := session.CallTool(ctx, &mcp.CallToolParams{
+		Name: "capture_screenshot_from_html",
+		Arguments: map[string]interface{}{
+			"html_content": htmlContent,
+			"selector":     "#card",
+			"padding":      10,
+		},
+	})
+	if err != nil {
+		t.Fatalf("capture_screenshot_from_html tool call failed: %v", err)
+	}
+
+	if len(result.Content) == 0 {
+		t.Fatal("Expected response content, got empty")
+	}
+
+	imageContent, ok := result.Content[0].(*mcp.ImageContent)
+	if !ok {
+		t.Fatal("Expected ImageContent response from selector screenshot")
+	}
+
+	validateImageContent(t, imageContent)
+	saveTestScreenshot(t, imageContent.Data)
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(imageContent.Data))
+	if err != nil {
+		t.Fatalf("Failed to decode image config: %v", err)
+	}
+
+	if config.Width != 220 || config.Height != 120 {
+		t.Errorf("Expected 220x120 element screenshot, got %dx%d", config.Width, config.Height)
+	}
+
+	// Selectors that match nothing or a zero size element should return clear errors
+	errorCases := map[string]string{
+		"#missing": "no element matches selector",
+		"#empty":   "has zero size",
+	}
+
+	for selector, expectedError := range errorCases {
+		result, err := session.CallTool(ctx, &mcp.CallToolParams{
+			Name: "capture_screenshot_from_html",
+			Arguments: map[string]interface{}{
+				"html_content": htmlContent,
+				"selector":     selector,
+			},
+		})
+		if err != nil {
+			t.Fatalf("capture_screenshot_from_html tool call failed: %v", err)
+		}
+
+		if !result.IsError {
+			t.Errorf("Expected error result for selector %s", selector)
+			continue
+		}
+
+		textContent, ok := result.Content[0].(*mcp.TextContent)
+		if !ok || !strings.Contains(textContent.Text, expectedError) {
+			t.Errorf("Expected error containing %q for selector %s, got %v", expectedError, selector, result.Content[0])
+		}
+	}
+
+	cancel()
+	wg.Wait()
+}
@@ -0,0 +1,425 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// Session tool argument structures
+
+type OpenPageArgs struct {
+	ContextName      string `json:"context_name,omitempty" jsonschema:"browser context whose settings the page uses (default: 'default')"`
+	URL              string `json:"url,omitempty" jsonschema:"URL to load after opening the page (default: a blank page)"`
+	WaitUntil        string `json:"wait_until,omitempty" jsonschema:"page load point to wait for: 'load', 'domcontentloaded' or 'networkidle' (default: 'load')"`
+	NetworkIdleMs    int    `json:"network_idle_ms,omitempty" jsonschema:"with networkidle, milliseconds without requests in flight that count as idle (default: 500)"`
+	NetworkIdleMaxMs int    `json:"network_idle_max_ms,omitempty" jsonschema:"with networkidle, cap in milliseconds on the wait (default: 3/4 of the context timeout)"`
+}
+
+type NavigateArgs struct {
+	SessionID        string `json:"session_id" jsonschema:"id of the page returned by open_page"`
+	URL              string `json:"url" jsonschema:"URL to load in the page"`
+	WaitUntil        string `json:"wait_until,omitempty" jsonschema:"page load point to wait for: 'load', 'domcontentloaded' or 'networkidle' (default: 'load')"`
+	NetworkIdleMs    int    `json:"network_idle_ms,omitempty" jsonschema:"with networkidle, milliseconds without requests in flight that count as idle (default: 500)"`
+	NetworkIdleMaxMs int    `json:"network_idle_max_ms,omitempty" jsonschema:"with networkidle, cap in milliseconds on the wait (default: 3/4 of the context timeout)"`
+}
+
+type ClickArgs struct {
+	SessionID string `json:"session_id" jsonschema:"id of the page returned by open_page"`
+	Selector  string `json:"selector" jsonschema:"CSS selector of the element to click, waits for it to appear"`
+}
+
+type TypeArgs struct {
+	SessionID  string `json:"session_id" jsonschema:"id of the page returned by open_page"`
+	Selector   string `json:"selector,omitempty" jsonschema:"CSS selector of the element to type into (default: the focused element)"`
+	Text       string `json:"text" jsonschema:"text to type"`
+	PressEnter bool   `json:"press_enter,omitempty" jsonschema:"press Enter after typing, e.g. to submit a form"`
+}
+
+type ScrollArgs struct {
+	SessionID string `json:"session_id" jsonschema:"id of the page returned by open_page"`
+	Selector  string `json:"selector,omitempty" jsonschema:"CSS selector of an element to scroll into view"`
+	X         int    `json:"x,omitempty" jsonschema:"pixels to scroll right, negative to scroll left (ignored with selector)"`
+	Y         int    `json:"y,omitempty" jsonschema:"pixels to scroll down, negative to scroll up (ignored with selector)"`
+}
+
+type EvaluateArgs struct {
+	SessionID  string `json:"session_id" jsonschema:"id of the page returned by open_page"`
+	Expression string `json:"expression" jsonschema:"JavaScript expression to evaluate in the page, a returned promise is awaited"`
+}
+
+type ScreenshotPageArgs struct {
+	SessionID     string   `json:"session_id" jsonschema:"id of the page returned by open_page"`
+	FullHeight    bool     `json:"full_height,omitempty" jsonschema:"capture full page height up to 10x the viewport height"`
+	Selector      string   `json:"selector,omitempty" jsonschema:"CSS selector of a single element to capture instead of the whole viewport"`
+	Padding       int      `json:"padding,omitempty" jsonschema:"padding in pixels around the element captured with selector (default: 0)"`
+	Clip          string   `json:"clip,omitempty" jsonschema:"capture only a region of the page given as 'x,y,w,h' in CSS pixels, can reach content below the fold"`
+	Format        string   `json:"format,omitempty" jsonschema:"image format: 'png', 'jpeg' or 'webp' (default: 'png')"`
+	Quality       int      `json:"quality,omitempty" jsonschema:"JPEG and WebP quality from 1 to 100 (default: 95 for jpeg, 90 for webp)"`
+	MaskSelectors []string `json:"mask_selectors,omitempty" jsonschema:"CSS selectors of elements to cover in the screenshot, e.g. emails, tokens and avatars"`
+	MaskStyle     string   `json:"mask_style,omitempty" jsonschema:"how mask_selectors elements are covered: 'solid' or 'blur' (default: 'solid')"`
+}
+
+type SessionArgs struct {
+	SessionID string `json:"session_id" jsonschema:"id of the page returned by open_page"`
+}
+
+// EvaluateResult holds the value of an expression evaluated in a session
+type EvaluateResult struct {
+	Success   bool         `json:"success"`
+	SessionID string       `json:"session_id"`
+	Result    any          `json:"result"`
+	Error     *ScriptError `json:"error,omitempty"`
+}
+
+// PageResult describes the state of a session's page after a tool call
+type PageResult struct {
+	Success     bool   `json:"success"`
+	SessionID   string `json:"session_id"`
+	URL         string `json:"url"`
+	Title       string `json:"title"`
+	ContentType string `json:"content_type,omitempty"`
+}
+
+// getSession looks up an open page by the session id passed to a tool
+func getSession(sessionID string) (*BrowserSession, error) {
+	if sessionID == "" {
+		return nil, fmt.Errorf("session_id is required")
+	}
+	return sessionManager.Get(sessionID)
+}
+
+// newPageResult reports the page's current URL and title
+func newPageResult(session *BrowserSession) PageResult {
+	url, title := session.Info()
+	return PageResult{
+		Success:   true,
+		SessionID: session.ID,
+		URL:       url,
+		Title:     title,
+	}
+}
+
+// pageResultContent summarizes a page result for clients that only read text
+func pageResultContent(result PageResult) *mcp.CallToolResult {
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: fmt.Sprintf("Page %s: %s (%s)", result.SessionID, result.URL, result.Title)},
+		},
+	}
+}
+
+// runSessionActions performs actions on a page and reports its state
+func runSessionActions(sessionID string, actions []Action) (*mcp.CallToolResult, PageResult, error) {
+	if err := validateActions(actions); err != nil {
+		return newErrorResult[PageResult](err)
+	}
+
+	session, err := getSession(sessionID)
+	if err != nil {
+		return newErrorResult[PageResult](err)
+	}
+
+	session.Lock()
+	defer session.Unlock()
+
+	if err := session.Run(actions); err != nil {
+		return newErrorResult[PageResult](err)
+	}
+
+	result := newPageResult(session)
+	return pageResultContent(result), result, nil
+}
+
+func handleOpenPage(ctx context.Context, request *mcp.CallToolRequest, args OpenPageArgs) (*mcp.CallToolResult, PageResult, error) {
+	contextName := args.ContextName
+	if contextName == "" {
+		contextName = "default"
+	}
+
+	config, exists := configManager.GetContext(contextName)
+	if !exists {
+		return newErrorResult[PageResult](fmt.Errorf("context not found: %s", contextName))
+	}
+
+	waitUntil, err := normalizeWaitUntil(args.WaitUntil)
+	if err != nil {
+		return newErrorResult[PageResult](err)
+	}
+
+	if err := validateNetworkIdle(args.NetworkIdleMs, args.NetworkIdleMaxMs); err != nil {
+		return newErrorResult[PageResult](err)
+	}
+
+	requestConfig, err := config.requestConfig()
+	if err != nil {
+		return newErrorResult[PageResult](err)
+	}
+
+	session, err := sessionManager.Open(contextName, requestConfig)
+	if err != nil {
+		return newErrorResult[PageResult](fmt.Errorf("open page failed: %v", err))
+	}
+
+	if args.URL != "" {
+		session.Lock()
+		err := session.Navigate(args.URL, waitUntil, args.NetworkIdleMs, args.NetworkIdleMaxMs)
+		session.Unlock()
+
+		if err != nil {
+			sessionManager.Close(session.ID)
+			return newErrorResult[PageResult](fmt.Errorf("open page failed: %v", err))
+		}
+	}
+
+	result := newPageResult(session)
+	return pageResultContent(result), result, nil
+}
+
+func handleNavigate(ctx context.Context, request *mcp.CallToolRequest, args NavigateArgs) (*mcp.CallToolResult, PageResult, error) {
+	if args.URL == "" {
+		return newErrorResult[PageResult](fmt.Errorf("URL is required"))
+	}
+
+	waitUntil, err := normalizeWaitUntil(args.WaitUntil)
+	if err != nil {
+		return newErrorResult[PageResult](err)
+	}
+
+	if err := validateNetworkIdle(args.NetworkIdleMs, args.NetworkIdleMaxMs); err != nil {
+		return newErrorResult[PageResult](err)
+	}
+
+	session, err := getSession(args.SessionID)
+	if err != nil {
+		return newErrorResult[PageResult](err)
+	}
+
+	session.Lock()
+	defer session.Unlock()
+
+	if err := session.Navigate(args.URL, waitUntil, args.NetworkIdleMs, args.NetworkIdleMaxMs); err != nil {
+		return newErrorResult[PageResult](fmt.Errorf("navigate failed: %v", err))
+	}
+
+	result := newPageResult(session)
+	return pageResultContent(result), result, nil
+}
+
+func handleClick(ctx context.Context, request *mcp.CallToolRequest, args ClickArgs) (*mcp.CallToolResult, PageResult, error) {
+	return runSessionActions(args.SessionID, []Action{
+		{Type: ActionClick, Selector: args.Selector},
+	})
+}
+
+func handleType(ctx context.Context, request *mcp.CallToolRequest, args TypeArgs) (*mcp.CallToolResult, PageResult, error) {
+	actions := []Action{
+		{Type: ActionType, Selector: args.Selector, Text: args.Text},
+	}
+	if args.PressEnter {
+		actions = append(actions, Action{Type: ActionPress, Key: "Enter"})
+	}
+
+	return runSessionActions(args.SessionID, actions)
+}
+
+func handleScroll(ctx context.Context, request *mcp.CallToolRequest, args ScrollArgs) (*mcp.CallToolResult, PageResult, error) {
+	if args.Selector != "" {
+		return runSessionActions(args.SessionID, []Action{
+			{Type: ActionScrollTo, Selector: args.Selector},
+		})
+	}
+
+	if args.X == 0 && args.Y == 0 {
+		return newErrorResult[PageResult](fmt.Errorf("selector, x or y is required"))
+	}
+
+	session, err := getSession(args.SessionID)
+	if err != nil {
+		return newErrorResult[PageResult](err)
+	}
+
+	session.Lock()
+	defer session.Unlock()
+
+	if _, err := session.Evaluate(fmt.Sprintf("window.scrollBy(%d, %d)", args.X, args.Y)); err != nil {
+		return newErrorResult[PageResult](fmt.Errorf("scroll failed: %v", err))
+	}
+
+	result := newPageResult(session)
+	return pageResultContent(result), result, nil
+}
+
+func handleEvaluate(ctx context.Context, request *mcp.CallToolRequest, args EvaluateArgs) (*mcp.CallToolResult, EvaluateResult, error) {
+	if strings.TrimSpace(args.Expression) == "" {
+		return newErrorResult[EvaluateResult](fmt.Errorf("expression is required"))
+	}
+
+	session, err := getSession(args.SessionID)
+	if err != nil {
+		return newErrorResult[EvaluateResult](err)
+	}
+
+	session.Lock()
+	defer session.Unlock()
+
+	raw, err := session.Evaluate(args.Expression)
+
+	// Exceptions thrown by the expression are reported with their position,
+	// like evaluate_javascript
+	var scriptErr *ScriptError
+	if errors.As(err, &scriptErr) {
+		result := EvaluateResult{
+			SessionID: session.ID,
+			Error:     scriptErr,
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("expression threw: %v", scriptErr)},
+			},
+			IsError: true,
+		}, result, nil
+	}
+
+	if err != nil {
+		return newErrorResult[EvaluateResult](fmt.Errorf("evaluate failed: %v", err))
+	}
+
+	var value any
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return newErrorResult[EvaluateResult](fmt.Errorf("invalid expression result: %v", err))
+	}
+
+	result := EvaluateResult{
+		Success:   true,
+		SessionID: session.ID,
+		Result:    value,
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: string(raw)},
+		},
+	}, result, nil
+}
+
+func handleScreenshotPage(ctx context.Context, request *mcp.CallToolRequest, args ScreenshotPageArgs) (*mcp.CallToolResult, PageResult, error) {
+	if args.Selector != "" && args.Clip != "" {
+		return newErrorResult[PageResult](fmt.Errorf("selector and clip cannot be used together"))
+	}
+
+	if args.Padding < 0 {
+		return newErrorResult[PageResult](fmt.Errorf("padding cannot be negative"))
+	}
+
+	clip, err := ParseClipString(args.Clip)
+	if err != nil {
+		return newErrorResult[PageResult](fmt.Errorf("invalid clip: %v", err))
+	}
+
+	format, err := normalizeImageFormat(args.Format)
+	if err != nil {
+		return newErrorResult[PageResult](err)
+	}
+
+	if err := validateQuality(args.Quality); err != nil {
+		return newErrorResult[PageResult](fmt.Errorf("invalid quality: %v", err))
+	}
+
+	maskStyle, err := normalizeMaskStyle(args.MaskStyle)
+	if err != nil {
+		return newErrorResult[PageResult](err)
+	}
+
+	session, err := getSession(args.SessionID)
+	if err != nil {
+		return newErrorResult[PageResult](err)
+	}
+
+	session.Lock()
+	defer session.Unlock()
+
+	// Copy the session settings so a full height capture doesn't change them
+	captureConfig := *session.Config
+	captureConfig.FullHeight = args.FullHeight
+	captureConfig.Selector = args.Selector
+	captureConfig.SelectorPadding = args.Padding
+	captureConfig.Clip = clip
+	captureConfig.Format = format
+	captureConfig.Quality = args.Quality
+	captureConfig.MaskSelectors = args.MaskSelectors
+	captureConfig.MaskStyle = maskStyle
+
+	screenshot, contentType, err := session.Screenshot(&captureConfig)
+	if err != nil {
+		return newErrorResult[PageResult](fmt.Errorf("screenshot failed: %v", err))
+	}
+
+	result := newPageResult(session)
+	result.ContentType = contentType
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.ImageContent{
+				Data:     screenshot,
+				MIMEType: contentType,
+			},
+		},
+	}, result, nil
+}
+
+func handleGetPageHTML(ctx context.Context, request *mcp.CallToolRequest, args SessionArgs) (*mcp.CallToolResult, map[string]interface{}, error) {
+	session, err := getSession(args.SessionID)
+	if err != nil {
+		return newErrorResult[map[string]interface{}](err)
+	}
+
+	session.Lock()
+	defer session.Unlock()
+
+	html, err := session.HTML()
+	if err != nil {
+		return newErrorResult[map[string]interface{}](fmt.Errorf("get HTML failed: %v", err))
+	}
+
+	url, _ := session.Info()
+
+	result := map[string]interface{}{
+		"success":    true,
+		"session_id": session.ID,
+		"html":       html,
+		"url":        url,
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: html},
+		},
+	}, result, nil
+}
+
+func handleClosePage(ctx context.Context, request *mcp.CallToolRequest, args SessionArgs) (*mcp.CallToolResult, map[string]interface{}, error) {
+	if args.SessionID == "" {
+		return newErrorResult[map[string]interface{}](fmt.Errorf("session_id is required"))
+	}
+
+	if !sessionManager.Close(args.SessionID) {
+		return newErrorResult[map[string]interface{}](fmt.Errorf("page not found: %s", args.SessionID))
+	}
+
+	result := map[string]interface{}{
+		"success":    true,
+		"session_id": args.SessionID,
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: fmt.Sprintf("Closed page %s", args.SessionID)},
+		},
+	}, result, nil
+}
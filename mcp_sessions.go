@@ -0,0 +1,332 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+const (
+	// maxBrowserSessions caps the number of pages kept open at once
+	maxBrowserSessions = 16
+
+	sessionCleanupInterval = 30 * time.Second
+)
+
+// BrowserSession is a page kept open across MCP tool calls so an agent can
+// navigate, interact with and capture the same page. Each session runs in its
+// own incognito browser context configured from a named context.
+type BrowserSession struct {
+	ID          string
+	ContextName string
+	Config      *RequestConfig
+	CreatedAt   time.Time
+	LastUsed    time.Time
+
+	page    *rod.Page
+	tracker *networkIdleTracker
+	cancel  func()
+	release func()
+	mutex   sync.Mutex
+}
+
+// SessionManager tracks open browser sessions and closes the ones left idle
+type SessionManager struct {
+	sessions    map[string]*BrowserSession
+	opening     int // Sessions being opened, counted against maxBrowserSessions
+	idleTimeout time.Duration
+	mutex       sync.Mutex
+}
+
+func NewSessionManager(idleTimeout time.Duration) *SessionManager {
+	manager := &SessionManager{
+		sessions:    make(map[string]*BrowserSession),
+		idleTimeout: idleTimeout,
+	}
+
+	if idleTimeout > 0 {
+		go manager.cleanupLoop(sessionCleanupInterval)
+	}
+
+	return manager
+}
+
+// Open creates a session with a blank page set up for the given context
+func (m *SessionManager) Open(contextName string, config *RequestConfig) (*BrowserSession, error) {
+	// Reserve a slot so concurrent opens can't go over the limit while the
+	// browser is starting
+	m.mutex.Lock()
+	if len(m.sessions)+m.opening >= maxBrowserSessions {
+		m.mutex.Unlock()
+		return nil, fmt.Errorf("too many open pages (limit %d), close one with close_page first", maxBrowserSessions)
+	}
+	m.opening++
+	m.mutex.Unlock()
+
+	defer func() {
+		m.mutex.Lock()
+		m.opening--
+		m.mutex.Unlock()
+	}()
+
+	browser, release, err := acquireBrowser(config.BrowserURL, config.Proxy)
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := browser.Page(proto.TargetCreateTarget{})
+	if err != nil {
+		release()
+		return nil, fmt.Errorf("failed to create page: %w", err)
+	}
+
+	// Event listeners and request hijacking live until the session is closed
+	ctx, cancel := context.WithCancel(context.Background())
+	page = page.Context(ctx)
+
+	session := &BrowserSession{
+		ID:          generateRequestID(),
+		ContextName: contextName,
+		Config:      config,
+		CreatedAt:   time.Now(),
+		LastUsed:    time.Now(),
+		page:        page,
+		cancel:      cancel,
+		release:     release,
+	}
+
+	// Domain filtering applies to every navigation in a session, but the
+	// first URL the agent opens is always allowed like a one-off request
+	hijackResult := setupRequestHijacking(page, &HijackConfig{
+		DomainWhitelist:    config.DomainWhitelist,
+		BlockDomains:       config.BlockDomains,
+		BlockResourceTypes: config.BlockResourceTypes,
+		AdblockList:        config.AdblockList,
+		CustomHeaders:      config.CustomHeaders,
+		HeaderRules:        config.HeaderRules,
+		Routes:             config.Routes,
+		NetworkPolicy:      config.NetworkPolicy,
+		Proxy:              config.Proxy,
+		Debug:              config.Debug,
+		PermitFirstRequest: true,
+		TrackNetworkIdle:   true,
+	})
+	session.tracker = hijackResult.NetworkIdle
+
+	if err := session.setup(); err != nil {
+		session.close()
+		return nil, err
+	}
+
+	m.mutex.Lock()
+	m.sessions[session.ID] = session
+	m.mutex.Unlock()
+
+	return session, nil
+}
+
+// setup applies the emulation, injected scripts, cookies and color scheme
+// before the first navigation
+func (s *BrowserSession) setup() error {
+	page, cancel := s.timeoutPage()
+	defer cancel()
+
+	if err := applyEmulation(page, s.Config); err != nil {
+		return err
+	}
+
+	if err := injectBeforeLoad(page, s.Config); err != nil {
+		return err
+	}
+
+	if len(s.Config.Cookies) > 0 {
+		if err := page.SetCookies(s.Config.Cookies); err != nil {
+			return fmt.Errorf("failed to set cookies: %v", err)
+		}
+	}
+
+	if s.Config.ColorScheme != "" {
+		_ = proto.EmulationSetEmulatedMedia{
+			Media: "screen",
+			Features: []*proto.EmulationMediaFeature{
+				{Name: "prefers-color-scheme", Value: s.Config.ColorScheme},
+			},
+		}.Call(page)
+	}
+
+	return nil
+}
+
+// Get returns an open session and marks it as used
+func (m *SessionManager) Get(sessionID string) (*BrowserSession, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	session, exists := m.sessions[sessionID]
+	if !exists {
+		return nil, fmt.Errorf("page not found: %s (it may have been closed after being idle)", sessionID)
+	}
+
+	session.LastUsed = time.Now()
+	return session, nil
+}
+
+// Close closes a session and its browser context
+func (m *SessionManager) Close(sessionID string) bool {
+	m.mutex.Lock()
+	session, exists := m.sessions[sessionID]
+	delete(m.sessions, sessionID)
+	m.mutex.Unlock()
+
+	if !exists {
+		return false
+	}
+
+	// Wait for any operation in progress to finish
+	session.mutex.Lock()
+	defer session.mutex.Unlock()
+
+	session.close()
+	return true
+}
+
+func (m *SessionManager) cleanupLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		m.CloseIdle()
+	}
+}
+
+// CloseIdle closes every session that hasn't been used within the idle
+// timeout and isn't running an operation
+func (m *SessionManager) CloseIdle() {
+	m.mutex.Lock()
+	var idle []*BrowserSession
+	for id, session := range m.sessions {
+		if time.Since(session.LastUsed) < m.idleTimeout || !session.mutex.TryLock() {
+			continue
+		}
+		delete(m.sessions, id)
+		idle = append(idle, session)
+	}
+	m.mutex.Unlock()
+
+	for _, session := range idle {
+		log.Printf("Closing page %s after being idle for %s", session.ID, m.idleTimeout)
+		session.close()
+		session.mutex.Unlock()
+	}
+}
+
+func (s *BrowserSession) close() {
+	s.cancel()
+	s.release()
+}
+
+// Lock serializes operations on the session's page
+func (s *BrowserSession) Lock() {
+	s.mutex.Lock()
+}
+
+func (s *BrowserSession) Unlock() {
+	s.mutex.Unlock()
+}
+
+// timeoutPage returns the session's page bounded by the context timeout
+func (s *BrowserSession) timeoutPage() (*rod.Page, func()) {
+	if s.Config.TimeoutSeconds <= 0 {
+		return waitContext(s.page)
+	}
+
+	ctx, cancel := context.WithTimeout(s.page.GetContext(), time.Duration(s.Config.TimeoutSeconds)*time.Second)
+	return s.page.Context(ctx), cancel
+}
+
+// Navigate loads url in the session's page and waits for waitUntil, idleMs
+// and maxWaitMs tune the networkidle wait
+func (s *BrowserSession) Navigate(url, waitUntil string, idleMs, maxWaitMs int) error {
+	page, cancel := s.timeoutPage()
+	defer cancel()
+
+	if !isStubbed(s.Config.Routes, url) {
+		if err := s.Config.NetworkPolicy.CheckURL(page.GetContext(), url); err != nil {
+			return err
+		}
+	}
+
+	if err := page.Navigate(url); err != nil {
+		return err
+	}
+
+	if err := waitForPage(page, waitUntil, idleMs, maxWaitMs, s.tracker); err != nil {
+		return err
+	}
+
+	return injectAfterLoad(page, s.Config)
+}
+
+// Run performs the actions in order on the session's page
+func (s *BrowserSession) Run(actions []Action) error {
+	page, cancel := s.timeoutPage()
+	defer cancel()
+
+	return runActions(page, actions)
+}
+
+// Evaluate runs a JavaScript expression in the page and returns the result
+// as JSON
+func (s *BrowserSession) Evaluate(expression string) (json.RawMessage, error) {
+	page, cancel := s.timeoutPage()
+	defer cancel()
+
+	return evaluateExpression(page, expression)
+}
+
+// Screenshot captures the page's current state. A full height capture resizes
+// the viewport, so it is restored afterwards for later interactions.
+func (s *BrowserSession) Screenshot(config *RequestConfig) ([]byte, string, error) {
+	page, cancel := s.timeoutPage()
+	defer cancel()
+
+	if config.FullHeight {
+		if err := adjustViewportForFullHeight(page, config); err != nil {
+			return nil, "", err
+		}
+		defer func() {
+			if err := applyEmulation(page, s.Config); err != nil {
+				log.Printf("Error restoring viewport of page %s: %v", s.ID, err)
+			}
+		}()
+	}
+
+	return captureScreenshot(page, config)
+}
+
+// HTML returns the page's current DOM serialized as HTML
+func (s *BrowserSession) HTML() (string, error) {
+	page, cancel := s.timeoutPage()
+	defer cancel()
+
+	return page.HTML()
+}
+
+// Info returns the URL and title of the page
+func (s *BrowserSession) Info() (string, string) {
+	page, cancel := s.timeoutPage()
+	defer cancel()
+
+	info, err := page.Info()
+	if err != nil {
+		return "", ""
+	}
+
+	return info.URL, info.Title
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/go-rod/rod"
+)
+
+// PageMetadata is the structured data a link preview needs, read from the
+// rendered page. URLs are resolved against the final page URL.
+type PageMetadata struct {
+	URL          string            `json:"url" jsonschema:"final URL of the page after redirects"`
+	Title        string            `json:"title,omitempty" jsonschema:"document title"`
+	Description  string            `json:"description,omitempty" jsonschema:"content of the description meta tag"`
+	CanonicalURL string            `json:"canonical_url,omitempty" jsonschema:"href of the canonical link"`
+	Lang         string            `json:"lang,omitempty" jsonschema:"lang attribute of the html element"`
+	OpenGraph    map[string]string `json:"open_graph,omitempty" jsonschema:"og: meta properties keyed by name without the prefix, e.g. title, image, type"`
+	Twitter      map[string]string `json:"twitter,omitempty" jsonschema:"twitter: meta properties keyed by name without the prefix, e.g. card, site, image"`
+	Favicons     []Favicon         `json:"favicons,omitempty" jsonschema:"icons linked by the page, including apple-touch-icon"`
+	JSONLD       []any             `json:"json_ld,omitempty" jsonschema:"parsed application/ld+json blocks, invalid blocks are skipped"`
+}
+
+// Favicon is an icon link declared by the page
+type Favicon struct {
+	URL   string `json:"url"`
+	Rel   string `json:"rel"`
+	Type  string `json:"type,omitempty"`
+	Sizes string `json:"sizes,omitempty"`
+}
+
+// extractMetadata reads the title, meta tags, icons and JSON-LD from the page
+func extractMetadata(page *rod.Page) (*PageMetadata, error) {
+	result, err := page.Eval(pageMetadataJS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract metadata: %w", err)
+	}
+
+	var metadata PageMetadata
+	if err := json.Unmarshal([]byte(result.Value.JSON("", "")), &metadata); err != nil {
+		return nil, fmt.Errorf("failed to decode metadata: %v", err)
+	}
+
+	return &metadata, nil
+}
+
+// pageMetadataJS collects the page metadata. When a meta property is repeated,
+// as og:image often is, the first occurrence wins.
+const pageMetadataJS = `() => {
+	const resolve = (href) => {
+		try {
+			return new URL(href, document.baseURI).href
+		} catch (e) {
+			return href
+		}
+	}
+
+	const metadata = {
+		url: location.href,
+		title: document.title.trim(),
+		lang: document.documentElement.lang || "",
+		open_graph: {},
+		twitter: {},
+		favicons: [],
+		json_ld: [],
+	}
+
+	for (const meta of document.querySelectorAll("meta[content]")) {
+		const key = (meta.getAttribute("property") || meta.getAttribute("name") || "").trim().toLowerCase()
+		const content = meta.getAttribute("content").trim()
+		if (!key || !content) {
+			continue
+		}
+
+		if (key === "description" && !metadata.description) {
+			metadata.description = content
+		} else if (key.startsWith("og:")) {
+			const name = key.slice(3)
+			if (!(name in metadata.open_graph)) {
+				metadata.open_graph[name] = /^(image|video|audio|url)(:url|:secure_url)?$/.test(name) ? resolve(content) : content
+			}
+		} else if (key.startsWith("twitter:")) {
+			const name = key.slice(8)
+			if (!(name in metadata.twitter)) {
+				metadata.twitter[name] = /^(image|player)(:src)?$/.test(name) ? resolve(content) : content
+			}
+		}
+	}
+
+	const canonical = document.querySelector("link[rel~=canonical][href]")
+	if (canonical) {
+		metadata.canonical_url = resolve(canonical.getAttribute("href"))
+	}
+
+	for (const link of document.querySelectorAll("link[rel][href]")) {
+		const rel = link.getAttribute("rel").toLowerCase().split(/\s+/)
+		if (!rel.some((value) => value === "icon" || value === "apple-touch-icon" || value === "apple-touch-icon-precomposed" || value === "mask-icon")) {
+			continue
+		}
+		metadata.favicons.push({
+			url: resolve(link.getAttribute("href")),
+			rel: rel.join(" "),
+			type: link.getAttribute("type") || "",
+			sizes: link.getAttribute("sizes") || "",
+		})
+	}
+
+	for (const script of document.querySelectorAll("script[type='application/ld+json']")) {
+		try {
+			metadata.json_ld.push(JSON.parse(script.textContent))
+		} catch (e) {
+			// Skip blocks that aren't valid JSON
+		}
+	}
+
+	return metadata
+}`
@@ -14,6 +14,10 @@ type Metrics struct {
 	SuccessRequests atomic.Int64  `metric:"sitecap_requests_success_total"`
 	FailedRequests  atomic.Int64  `metric:"sitecap_requests_failed_total"`
 	TotalDuration   atomic.Uint64 `metric:"sitecap_duration_seconds_total"`
+
+	RejectedRequests  atomic.Int64  `metric:"sitecap_requests_rejected_total"`
+	QueueDepth        atomic.Int64  `metric:"sitecap_queue_depth"`
+	QueueWaitDuration atomic.Uint64 `metric:"sitecap_queue_wait_seconds_total"`
 }
 
 var metrics Metrics
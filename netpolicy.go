@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const networkPolicyLookupTimeout = 5 * time.Second
+
+// blockedPrefixes are the special purpose ranges not covered by the netip
+// helpers used in isBlockedAddr
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),     // "This" network
+	netip.MustParsePrefix("100.64.0.0/10"), // Carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),  // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"), // Benchmarking
+	netip.MustParsePrefix("240.0.0.0/4"),   // Reserved and broadcast
+	netip.MustParsePrefix("64:ff9b::/96"),  // NAT64, can embed any IPv4 address
+}
+
+// blockedHostnames are cloud metadata endpoints reachable by name
+var blockedHostnames = []string{
+	"metadata.google.internal",
+	"metadata.goog",
+}
+
+// NetworkPolicy blocks requests to hosts that resolve to private, loopback,
+// link-local and cloud metadata addresses, so a caller can't use the browser
+// to reach internal services. A nil policy allows everything.
+type NetworkPolicy struct {
+	Allow []string // Host patterns, IP addresses or CIDR ranges exempt from the policy
+}
+
+// ParseNetworkAllowlist parses a comma-separated list of host patterns, IP
+// addresses and CIDR ranges
+func ParseNetworkAllowlist(value string) ([]string, error) {
+	var allow []string
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		if strings.Contains(entry, "/") {
+			if _, err := netip.ParsePrefix(entry); err != nil {
+				return nil, fmt.Errorf("invalid CIDR range %q", entry)
+			}
+		}
+
+		allow = append(allow, entry)
+	}
+
+	return allow, nil
+}
+
+// isBlockedAddr reports whether addr is in a range the policy blocks
+func isBlockedAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || addr.IsMulticast() {
+		return true
+	}
+
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// allowsHost reports whether hostname matches an allowed host pattern
+func (p *NetworkPolicy) allowsHost(hostname string) bool {
+	return matchesDomainPattern(hostname, p.Allow)
+}
+
+// allowsAddr reports whether addr is an allowed address or in an allowed range
+func (p *NetworkPolicy) allowsAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+
+	for _, entry := range p.Allow {
+		if prefix, err := netip.ParsePrefix(entry); err == nil {
+			if prefix.Contains(addr) {
+				return true
+			}
+		} else if allowed, err := netip.ParseAddr(entry); err == nil && allowed.Unmap() == addr {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CheckURL returns an error if the policy blocks requests to requestURL.
+// Hostnames are resolved and blocked if any of their addresses is.
+func (p *NetworkPolicy) CheckURL(ctx context.Context, requestURL string) error {
+	if p == nil {
+		return nil
+	}
+
+	parsed, err := url.Parse(requestURL)
+	if err != nil {
+		return fmt.Errorf("blocked by network policy: invalid URL")
+	}
+
+	switch parsed.Scheme {
+	case "http", "https", "ws", "wss":
+	case "data", "blob", "about":
+		// Served by the browser without a network request
+		return nil
+	default:
+		return fmt.Errorf("blocked by network policy: %s URLs are not allowed", parsed.Scheme)
+	}
+
+	hostname := strings.ToLower(strings.TrimSuffix(parsed.Hostname(), "."))
+	if p.allowsHost(hostname) {
+		return nil
+	}
+
+	for _, blocked := range blockedHostnames {
+		if hostname == blocked {
+			return fmt.Errorf("blocked by network policy: %s is a cloud metadata host", hostname)
+		}
+	}
+
+	var addrs []netip.Addr
+	if addr, err := netip.ParseAddr(hostname); err == nil {
+		addrs = []netip.Addr{addr}
+	} else {
+		ctx, cancel := context.WithTimeout(ctx, networkPolicyLookupTimeout)
+		defer cancel()
+
+		addrs, err = net.DefaultResolver.LookupNetIP(ctx, "ip", hostname)
+		if err != nil {
+			// The browser resolves the host again by itself, so a lookup
+			// that fails or times out here can't be let through
+			return fmt.Errorf("blocked by network policy: lookup failed for %s", hostname)
+		}
+	}
+
+	for _, addr := range addrs {
+		addr = addr.Unmap()
+		if !isBlockedAddr(addr) || p.allowsAddr(addr) {
+			continue
+		}
+		if addr.String() == hostname {
+			return fmt.Errorf("blocked by network policy: %s is a private address", addr)
+		}
+		return fmt.Errorf("blocked by network policy: %s resolves to private address %s", hostname, addr)
+	}
+
+	return nil
+}
+
+// networkPolicyChecker checks the requests of one page, resolving each host
+// only once
+type networkPolicyChecker struct {
+	policy  *NetworkPolicy
+	results sync.Map // host -> error (nil if allowed)
+}
+
+func newNetworkPolicyChecker(policy *NetworkPolicy) *networkPolicyChecker {
+	return &networkPolicyChecker{policy: policy}
+}
+
+func (c *networkPolicyChecker) Check(ctx context.Context, requestURL string) error {
+	parsed, err := url.Parse(requestURL)
+	if err != nil {
+		return c.policy.CheckURL(ctx, requestURL)
+	}
+
+	key := parsed.Scheme + "://" + parsed.Host
+	if result, exists := c.results.Load(key); exists {
+		if result == nil {
+			return nil
+		}
+		return result.(error)
+	}
+
+	err = c.policy.CheckURL(ctx, requestURL)
+	c.results.Store(key, err)
+	return err
+}
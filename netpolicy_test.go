@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+)
+
+func TestIsBlockedAddr(t *testing.T) {
+	tests := []struct {
+		addr    string
+		blocked bool
+	}{
+		{"127.0.0.1", true},
+		{"::1", true},
+		{"10.1.2.3", true},
+		{"172.16.0.1", true},
+		{"192.168.1.1", true},
+		{"169.254.169.254", true},
+		{"fe80::1", true},
+		{"fc00::1", true},
+		{"fd12:3456::1", true},
+		{"0.0.0.0", true},
+		{"0.1.2.3", true},
+		{"100.64.0.1", true},
+		{"100.127.255.254", true},
+		{"198.18.0.1", true},
+		{"224.0.0.1", true},
+		{"255.255.255.255", true},
+		{"::ffff:127.0.0.1", true},
+		{"::ffff:169.254.169.254", true},
+		{"64:ff9b::a9fe:a9fe", true},
+		{"::", true},
+		{"8.8.8.8", false},
+		{"100.128.0.1", false},
+		{"172.32.0.1", false},
+		{"::ffff:8.8.8.8", false},
+		{"2606:4700:4700::1111", false},
+	}
+
+	for _, tt := range tests {
+		addr := netip.MustParseAddr(tt.addr)
+		if got := isBlockedAddr(addr); got != tt.blocked {
+			t.Errorf("isBlockedAddr(%s): expected %v, got %v", tt.addr, tt.blocked, got)
+		}
+	}
+}
+
+func TestNetworkPolicyAllowsAddr(t *testing.T) {
+	policy := &NetworkPolicy{Allow: []string{"10.1.0.0/16", "192.168.1.5", "fd00::/8", "internal.example.com"}}
+
+	tests := []struct {
+		addr    string
+		allowed bool
+	}{
+		{"10.1.2.3", true},
+		{"10.2.0.1", false},
+		{"192.168.1.5", true},
+		{"192.168.1.6", false},
+		{"::ffff:10.1.0.1", true},
+		{"fd00::1", true},
+		{"fc00::1", false},
+	}
+
+	for _, tt := range tests {
+		addr := netip.MustParseAddr(tt.addr)
+		if got := policy.allowsAddr(addr); got != tt.allowed {
+			t.Errorf("allowsAddr(%s): expected %v, got %v", tt.addr, tt.allowed, got)
+		}
+	}
+}
+
+func TestNetworkPolicyCheckURL(t *testing.T) {
+	policy := &NetworkPolicy{Allow: []string{"localhost", "10.1.0.0/16"}}
+
+	tests := []struct {
+		url     string
+		blocked bool
+	}{
+		{"http://127.0.0.1/", true},
+		{"https://[::1]:8443/admin", true},
+		{"http://169.254.169.254/latest/meta-data/", true},
+		{"http://[::ffff:169.254.169.254]/", true},
+		{"ws://192.168.1.1/socket", true},
+		{"http://metadata.google.internal/", true},
+		{"http://METADATA.GOOGLE.INTERNAL./", true},
+		{"file:///etc/passwd", true},
+		{"ftp://example.com/", true},
+		{"chrome://settings", true},
+		{"http://8.8.8.8/", false},
+		{"https://1.1.1.1/", false},
+		{"data:text/html,hello", false},
+		{"about:blank", false},
+		{"blob:https://example.com/1234", false},
+		{"http://localhost:3000/", false},
+		{"http://10.1.4.2/", false},
+		{"http://10.2.4.2/", true},
+	}
+
+	for _, tt := range tests {
+		err := policy.CheckURL(context.Background(), tt.url)
+		if tt.blocked && err == nil {
+			t.Errorf("CheckURL(%s): expected blocked, got allowed", tt.url)
+		} else if !tt.blocked && err != nil {
+			t.Errorf("CheckURL(%s): expected allowed, got %v", tt.url, err)
+		}
+	}
+}
+
+func TestNilNetworkPolicyAllowsEverything(t *testing.T) {
+	var policy *NetworkPolicy
+	if err := policy.CheckURL(context.Background(), "http://127.0.0.1/"); err != nil {
+		t.Errorf("Expected nil policy to allow everything, got %v", err)
+	}
+}
+
+func TestParseNetworkAllowlist(t *testing.T) {
+	allow, err := ParseNetworkAllowlist(" localhost, 10.0.0.0/8 ,,fd00::/8,*.corp.example.com,192.168.1.1 ")
+	if err != nil {
+		t.Fatalf("Expected allowlist to parse, got %v", err)
+	}
+
+	expected := []string{"localhost", "10.0.0.0/8", "fd00::/8", "*.corp.example.com", "192.168.1.1"}
+	if len(allow) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, allow)
+	}
+	for i := range expected {
+		if allow[i] != expected[i] {
+			t.Errorf("Expected entry %d to be %q, got %q", i, expected[i], allow[i])
+		}
+	}
+
+	for _, value := range []string{"10.0.0.0/33", "not-a-range/8", "10.0.0.1/"} {
+		if _, err := ParseNetworkAllowlist(value); err == nil {
+			t.Errorf("Expected error for %q", value)
+		}
+	}
+
+	allow, err = ParseNetworkAllowlist("")
+	if err != nil || len(allow) != 0 {
+		t.Errorf("Expected empty allowlist, got %v, %v", allow, err)
+	}
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// PDFOptions controls how a page is printed to PDF. Lengths are in inches and
+// zero or nil values use Chrome's defaults (US Letter with 1cm margins).
+type PDFOptions struct {
+	PaperWidth      float64
+	PaperHeight     float64
+	Margins         *PDFMargins
+	Landscape       bool
+	PrintBackground bool
+	HeaderTemplate  string
+	FooterTemplate  string
+	PageRanges      string
+}
+
+type PDFMargins struct {
+	Top    float64
+	Right  float64
+	Bottom float64
+	Left   float64
+}
+
+// paperSizes holds common paper sizes in inches, portrait orientation
+var paperSizes = map[string][2]float64{
+	"letter":  {8.5, 11},
+	"legal":   {8.5, 14},
+	"tabloid": {11, 17},
+	"ledger":  {17, 11},
+	"a3":      {11.69, 16.54},
+	"a4":      {8.27, 11.69},
+	"a5":      {5.83, 8.27},
+	"a6":      {4.13, 5.83},
+}
+
+// lengthUnits converts each supported unit to inches
+var lengthUnits = map[string]float64{
+	"in": 1,
+	"cm": 1 / 2.54,
+	"mm": 1 / 25.4,
+	"px": 1.0 / 96,
+}
+
+var pageRangesPattern = regexp.MustCompile(`^\s*\d+\s*(-\s*\d*\s*)?(,\s*\d+\s*(-\s*\d*\s*)?)*$`)
+
+// splitLengthUnit removes a trailing unit from a length, defaulting to inches
+func splitLengthUnit(value string) (string, float64) {
+	for unit, factor := range lengthUnits {
+		if strings.HasSuffix(value, unit) {
+			return strings.TrimSuffix(value, unit), factor
+		}
+	}
+	return value, 1
+}
+
+// parseLength parses a length like 0.5, 0.5in, 1cm, 10mm or 48px into inches
+func parseLength(value string) (float64, error) {
+	number, factor := splitLengthUnit(strings.ToLower(strings.TrimSpace(value)))
+
+	parsed, err := strconv.ParseFloat(number, 64)
+	if err != nil || parsed < 0 {
+		return 0, fmt.Errorf("invalid length %q, must be a non-negative number with an optional unit (in, cm, mm, px)", value)
+	}
+
+	return parsed * factor, nil
+}
+
+// ParsePaperSize parses a named paper size (letter, legal, tabloid, ledger,
+// a3, a4, a5, a6) or custom dimensions like 8.5x11in or 210x297mm into inches
+func ParsePaperSize(paper string) (float64, float64, error) {
+	paper = strings.ToLower(strings.TrimSpace(paper))
+	if paper == "" {
+		return 0, 0, nil
+	}
+
+	if size, exists := paperSizes[paper]; exists {
+		return size[0], size[1], nil
+	}
+
+	dimensions, factor := splitLengthUnit(paper)
+	parts := strings.Split(dimensions, "x")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("invalid paper size %q, expected a name like a4 or dimensions like 8.5x11in", paper)
+	}
+
+	width, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil || width <= 0 {
+		return 0, 0, fmt.Errorf("invalid paper width")
+	}
+
+	height, err := strconv.ParseFloat(parts[1], 64)
+	if err != nil || height <= 0 {
+		return 0, 0, fmt.Errorf("invalid paper height")
+	}
+
+	return width * factor, height * factor, nil
+}
+
+// ParseMargins parses margins using CSS shorthand: one to four lengths
+// separated by commas or spaces for top, right, bottom and left
+func ParseMargins(margin string) (*PDFMargins, error) {
+	fields := strings.FieldsFunc(margin, func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	if len(fields) > 4 {
+		return nil, fmt.Errorf("invalid margin, expected 1 to 4 values")
+	}
+
+	values := make([]float64, len(fields))
+	for i, field := range fields {
+		value, err := parseLength(field)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+
+	switch len(values) {
+	case 1:
+		return &PDFMargins{values[0], values[0], values[0], values[0]}, nil
+	case 2:
+		return &PDFMargins{values[0], values[1], values[0], values[1]}, nil
+	case 3:
+		return &PDFMargins{values[0], values[1], values[2], values[1]}, nil
+	default:
+		return &PDFMargins{values[0], values[1], values[2], values[3]}, nil
+	}
+}
+
+// parsePDFOptions builds PDF options from the string forms used by the
+// command line, HTTP and MCP interfaces
+func parsePDFOptions(paper, margin, pageRanges, headerTemplate, footerTemplate string, landscape, printBackground bool) (*PDFOptions, error) {
+	options := &PDFOptions{
+		Landscape:       landscape,
+		PrintBackground: printBackground,
+		HeaderTemplate:  headerTemplate,
+		FooterTemplate:  footerTemplate,
+		PageRanges:      strings.TrimSpace(pageRanges),
+	}
+
+	var err error
+	options.PaperWidth, options.PaperHeight, err = ParsePaperSize(paper)
+	if err != nil {
+		return nil, err
+	}
+
+	options.Margins, err = ParseMargins(margin)
+	if err != nil {
+		return nil, err
+	}
+
+	if options.PageRanges != "" && !pageRangesPattern.MatchString(options.PageRanges) {
+		return nil, fmt.Errorf("invalid page ranges %q, expected a list like '1-5, 8, 11-13'", pageRanges)
+	}
+
+	return options, nil
+}
+
+// printPDF renders the page with Page.printToPDF
+func printPDF(page *rod.Page, options *PDFOptions) ([]byte, error) {
+	if options == nil {
+		options = &PDFOptions{}
+	}
+
+	request := &proto.PagePrintToPDF{
+		Landscape:       options.Landscape,
+		PrintBackground: options.PrintBackground,
+		PageRanges:      options.PageRanges,
+	}
+
+	if options.PaperWidth > 0 && options.PaperHeight > 0 {
+		request.PaperWidth = &options.PaperWidth
+		request.PaperHeight = &options.PaperHeight
+	}
+
+	if options.Margins != nil {
+		request.MarginTop = &options.Margins.Top
+		request.MarginRight = &options.Margins.Right
+		request.MarginBottom = &options.Margins.Bottom
+		request.MarginLeft = &options.Margins.Left
+	}
+
+	// Chrome prints a default header and footer when only one template is
+	// given, so fill the other with an empty element
+	if options.HeaderTemplate != "" || options.FooterTemplate != "" {
+		request.DisplayHeaderFooter = true
+		request.HeaderTemplate = options.HeaderTemplate
+		request.FooterTemplate = options.FooterTemplate
+		if request.HeaderTemplate == "" {
+			request.HeaderTemplate = "<span></span>"
+		}
+		if request.FooterTemplate == "" {
+			request.FooterTemplate = "<span></span>"
+		}
+	}
+
+	stream, err := page.PDF(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to print PDF: %w", err)
+	}
+
+	pdf, err := io.ReadAll(stream)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF: %w", err)
+	}
+
+	return pdf, nil
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	ErrQueueFull    = errors.New("request queue is full")
+	ErrQueueTimeout = errors.New("timed out waiting for a free worker")
+)
+
+// requestQueue limits how many HTTP requests run a browser at once. When nil
+// requests are not limited.
+var requestQueue *RequestQueue
+
+// RequestQueue is a bounded worker queue. At most maxConcurrency requests run
+// at the same time, and at most maxDepth more wait for a free worker. Waiting
+// requests get workers in the order they arrived.
+type RequestQueue struct {
+	maxConcurrency int
+	maxDepth       int
+	waitTimeout    time.Duration
+
+	active  int        // Requests holding a worker
+	waiters *list.List // Channels of the waiting requests, oldest first
+	mutex   sync.Mutex
+}
+
+func NewRequestQueue(maxConcurrency, maxDepth int, waitTimeout time.Duration) *RequestQueue {
+	if maxConcurrency < 1 {
+		maxConcurrency = 1
+	}
+	if maxDepth < 0 {
+		maxDepth = 0
+	}
+
+	return &RequestQueue{
+		maxConcurrency: maxConcurrency,
+		maxDepth:       maxDepth,
+		waitTimeout:    waitTimeout,
+		waiters:        list.New(),
+	}
+}
+
+// Acquire blocks until a worker is free and returns a function that must be
+// called to release it. Returns ErrQueueFull without waiting if the queue is
+// already at its maximum depth.
+func (q *RequestQueue) Acquire(ctx context.Context) (func(), error) {
+	q.mutex.Lock()
+
+	// Fast path: a worker is free and nobody is waiting for it
+	if q.active < q.maxConcurrency && q.waiters.Len() == 0 {
+		q.active++
+		q.mutex.Unlock()
+		return q.release, nil
+	}
+
+	if q.waiters.Len() >= q.maxDepth {
+		q.mutex.Unlock()
+		return nil, ErrQueueFull
+	}
+
+	ready := make(chan struct{})
+	waiter := q.waiters.PushBack(ready)
+	q.mutex.Unlock()
+
+	metrics.QueueDepth.Add(1)
+	start := time.Now()
+	defer func() {
+		metrics.QueueDepth.Add(-1)
+		metrics.QueueWaitDuration.Add(uint64(time.Since(start).Nanoseconds()))
+	}()
+
+	var timeout <-chan time.Time
+	if q.waitTimeout > 0 {
+		timer := time.NewTimer(q.waitTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	var err error
+	select {
+	case <-ready:
+		return q.release, nil
+	case <-timeout:
+		err = ErrQueueTimeout
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	q.mutex.Lock()
+	select {
+	case <-ready:
+		// A worker was handed over while giving up, pass it on
+		q.mutex.Unlock()
+		q.release()
+	default:
+		q.waiters.Remove(waiter)
+		q.mutex.Unlock()
+	}
+
+	return nil, err
+}
+
+// release hands the worker to the oldest waiting request, or frees it
+func (q *RequestQueue) release() {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if front := q.waiters.Front(); front != nil {
+		q.waiters.Remove(front)
+		close(front.Value.(chan struct{}))
+		return
+	}
+	q.active--
+}
+
+// Depth returns the number of requests currently waiting for a worker
+func (q *RequestQueue) Depth() int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	return q.waiters.Len()
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRequestQueueLimitsConcurrency(t *testing.T) {
+	queue := NewRequestQueue(1, 1, 0)
+
+	release, err := queue.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Expected first request to acquire a worker, got %v", err)
+	}
+
+	acquired := make(chan func())
+	go func() {
+		secondRelease, err := queue.Acquire(context.Background())
+		if err != nil {
+			t.Errorf("Expected queued request to eventually acquire a worker, got %v", err)
+			close(acquired)
+			return
+		}
+		acquired <- secondRelease
+	}()
+
+	// Wait for the second request to enter the queue
+	deadline := time.Now().Add(time.Second)
+	for queue.Depth() != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("Second request never entered the queue")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if _, err := queue.Acquire(context.Background()); err != ErrQueueFull {
+		t.Errorf("Expected ErrQueueFull when queue is at max depth, got %v", err)
+	}
+
+	release()
+
+	select {
+	case secondRelease := <-acquired:
+		if secondRelease != nil {
+			secondRelease()
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Queued request did not acquire worker after release")
+	}
+
+	if queue.Depth() != 0 {
+		t.Errorf("Expected empty queue, got depth %d", queue.Depth())
+	}
+}
+
+func TestRequestQueueWaitTimeout(t *testing.T) {
+	queue := NewRequestQueue(1, 1, 10*time.Millisecond)
+
+	release, err := queue.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Expected first request to acquire a worker, got %v", err)
+	}
+	defer release()
+
+	if _, err := queue.Acquire(context.Background()); err != ErrQueueTimeout {
+		t.Errorf("Expected ErrQueueTimeout, got %v", err)
+	}
+}
+
+func TestRequestQueueZeroDepthRejectsWhenBusy(t *testing.T) {
+	queue := NewRequestQueue(1, 0, 0)
+
+	release, err := queue.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Expected first request to acquire a worker, got %v", err)
+	}
+	defer release()
+
+	if _, err := queue.Acquire(context.Background()); err != ErrQueueFull {
+		t.Errorf("Expected ErrQueueFull with zero queue depth, got %v", err)
+	}
+}
+
+// waitForDepth waits until depth requests are waiting in the queue
+func waitForDepth(t *testing.T, queue *RequestQueue, depth int) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for queue.Depth() != depth {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected queue depth %d, got %d", depth, queue.Depth())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestRequestQueueServesWaitersInOrder(t *testing.T) {
+	queue := NewRequestQueue(1, 3, 0)
+
+	release, err := queue.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Expected first request to acquire a worker, got %v", err)
+	}
+
+	order := make(chan int, 3)
+	for i := 0; i < 3; i++ {
+		go func() {
+			waiterRelease, err := queue.Acquire(context.Background())
+			if err != nil {
+				t.Errorf("Expected waiter %d to acquire a worker, got %v", i, err)
+				order <- -1
+				return
+			}
+			order <- i
+			waiterRelease()
+		}()
+		waitForDepth(t, queue, i+1)
+	}
+
+	release()
+
+	for expected := 0; expected < 3; expected++ {
+		select {
+		case got := <-order:
+			if got != expected {
+				t.Errorf("Expected waiter %d to acquire next, got %d", expected, got)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Waiter %d never acquired a worker", expected)
+		}
+	}
+}
+
+func TestRequestQueueNewArrivalsDontSkipWaiters(t *testing.T) {
+	queue := NewRequestQueue(1, 1, 0)
+
+	release, err := queue.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Expected first request to acquire a worker, got %v", err)
+	}
+
+	acquired := make(chan func())
+	go func() {
+		waiterRelease, err := queue.Acquire(context.Background())
+		if err != nil {
+			t.Errorf("Expected waiter to acquire a worker, got %v", err)
+			close(acquired)
+			return
+		}
+		acquired <- waiterRelease
+	}()
+	waitForDepth(t, queue, 1)
+
+	// The freed worker goes to the waiter, so a new arrival has to queue
+	// instead of taking it
+	release()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if newRelease, err := queue.Acquire(ctx); err == nil {
+		newRelease()
+		t.Error("Expected new arrival not to take the worker handed to the waiter")
+	}
+
+	select {
+	case waiterRelease := <-acquired:
+		if waiterRelease != nil {
+			waiterRelease()
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Waiter did not acquire the released worker")
+	}
+}
+
+func TestRequestQueueCanceledWaiter(t *testing.T) {
+	queue := NewRequestQueue(1, 1, 0)
+
+	release, err := queue.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Expected first request to acquire a worker, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan error)
+	go func() {
+		_, err := queue.Acquire(ctx)
+		result <- err
+	}()
+	waitForDepth(t, queue, 1)
+
+	cancel()
+	if err := <-result; err != context.Canceled {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if queue.Depth() != 0 {
+		t.Errorf("Expected canceled waiter to leave the queue, got depth %d", queue.Depth())
+	}
+
+	// The worker is freed rather than handed to the canceled waiter
+	release()
+	secondRelease, err := queue.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Expected worker to be free, got %v", err)
+	}
+	secondRelease()
+}
@@ -27,6 +27,80 @@ func initVips() {
 	})
 }
 
+// Default quality used for lossy formats when none is requested
+const (
+	defaultJpegQuality = 95
+	defaultWebpQuality = 90
+)
+
+// normalizeImageFormat validates an output format name, returning "png",
+// "jpeg", "webp" or "" when no format was given
+func normalizeImageFormat(format string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "":
+		return "", nil
+	case "png":
+		return "png", nil
+	case "jpeg", "jpg":
+		return "jpeg", nil
+	case "webp":
+		return "webp", nil
+	default:
+		return "", fmt.Errorf("invalid format %q, must be png, jpeg or webp", format)
+	}
+}
+
+// ParseQualityString parses an image quality from 1 to 100, 0 for the default
+func ParseQualityString(quality string) (int, error) {
+	if quality == "" {
+		return 0, nil
+	}
+
+	value, err := strconv.Atoi(quality)
+	if err != nil {
+		return 0, fmt.Errorf("quality must be a number")
+	}
+
+	if err := validateQuality(value); err != nil {
+		return 0, err
+	}
+
+	return value, nil
+}
+
+func validateQuality(quality int) error {
+	if quality < 0 || quality > 100 {
+		return fmt.Errorf("quality must be between 1 and 100")
+	}
+	return nil
+}
+
+// imageQuality returns the quality to encode a lossy format with
+func imageQuality(format string, quality int) int {
+	if quality > 0 {
+		return quality
+	}
+	if format == "webp" {
+		return defaultWebpQuality
+	}
+	return defaultJpegQuality
+}
+
+// formatImageType maps an output format name to the libvips image type,
+// returning ImageTypeUnknown for an empty format
+func formatImageType(format string) vips.ImageType {
+	switch format {
+	case "png":
+		return vips.ImageTypePng
+	case "jpeg":
+		return vips.ImageTypeJpeg
+	case "webp":
+		return vips.ImageTypeWebp
+	default:
+		return vips.ImageTypeUnknown
+	}
+}
+
 type ResizeParams struct {
 	Width       int
 	Height      int
@@ -146,11 +220,13 @@ func getContentType(imageType vips.ImageType) string {
 	}
 }
 
-func exportImage(image *vips.Image, format vips.ImageType) ([]byte, error) {
+// exportImage encodes the image, quality only applies to JPEG and WebP and
+// uses the format's default when 0
+func exportImage(image *vips.Image, format vips.ImageType, quality int) ([]byte, error) {
 	switch format {
 	case vips.ImageTypeJpeg:
 		opts := vips.DefaultJpegsaveBufferOptions()
-		opts.Q = 95
+		opts.Q = imageQuality("jpeg", quality)
 		return image.JpegsaveBuffer(opts)
 	case vips.ImageTypePng:
 		opts := vips.DefaultPngsaveBufferOptions()
@@ -158,7 +234,7 @@ func exportImage(image *vips.Image, format vips.ImageType) ([]byte, error) {
 		return image.PngsaveBuffer(opts)
 	case vips.ImageTypeWebp:
 		opts := vips.DefaultWebpsaveBufferOptions()
-		opts.Q = 90
+		opts.Q = imageQuality("webp", quality)
 		return image.WebpsaveBuffer(opts)
 	case vips.ImageTypeGif:
 		return image.GifsaveBuffer(nil)
@@ -169,7 +245,10 @@ func exportImage(image *vips.Image, format vips.ImageType) ([]byte, error) {
 	}
 }
 
-func resizeImage(buf []byte, params *ResizeParams) ([]byte, vips.ImageType, error) {
+// processImage masks the given rectangles, resizes the image when params is
+// set and encodes it as outputFormat, keeping the source format when
+// outputFormat is ImageTypeUnknown
+func processImage(buf []byte, masks []MaskRect, maskStyle string, params *ResizeParams, outputFormat vips.ImageType, quality int) ([]byte, vips.ImageType, error) {
 	// Initialize vips if not already done
 	initVips()
 
@@ -184,11 +263,31 @@ func resizeImage(buf []byte, params *ResizeParams) ([]byte, vips.ImageType, erro
 		return nil, vips.ImageTypeUnknown, err
 	}
 
+	// Mask before resizing, the rectangles are in captured pixels
+	if err := maskImage(image, masks, maskStyle); err != nil {
+		return nil, format, err
+	}
+
+	if params != nil {
+		if err := resizeVipsImage(image, params); err != nil {
+			return nil, format, err
+		}
+	}
+
+	if outputFormat != vips.ImageTypeUnknown {
+		format = outputFormat
+	}
+
+	processed, err := exportImage(image, format, quality)
+	return processed, format, err
+}
+
+func resizeVipsImage(image *vips.Image, params *ResizeParams) error {
 	// manual cropping, no scaling takes place
 	if params.Crop {
-		err = image.ExtractArea(params.CropOffsetX, params.CropOffsetY, params.Width, params.Height)
+		err := image.ExtractArea(params.CropOffsetX, params.CropOffsetY, params.Width, params.Height)
 		if err != nil {
-			return nil, format, err
+			return err
 		}
 	} else {
 		// source dimensions
@@ -223,21 +322,21 @@ func resizeImage(buf []byte, params *ResizeParams) ([]byte, vips.ImageType, erro
 				}
 			}
 			if scaleRatio <= 0 {
-				return nil, format, fmt.Errorf("invalid scale ratio")
+				return fmt.Errorf("invalid scale ratio")
 			}
 			if err := image.Resize(scaleRatio, nil); err != nil {
-				return nil, format, err
+				return err
 			}
 		} else {
 			widthScale := float64(targetWidth) / float64(width)
 			heightScale := float64(targetHeight) / float64(height)
 			if widthScale <= 0 || heightScale <= 0 {
-				return nil, format, fmt.Errorf("invalid scale ratio")
+				return fmt.Errorf("invalid scale ratio")
 			}
 			opts := vips.DefaultResizeOptions()
 			opts.Vscale = heightScale
 			if err := image.Resize(widthScale, opts); err != nil {
-				return nil, format, err
+				return err
 			}
 		}
 
@@ -245,13 +344,65 @@ func resizeImage(buf []byte, params *ResizeParams) ([]byte, vips.ImageType, erro
 			// Center crop
 			left := (image.Width() - params.Width) / 2
 			top := (image.Height() - params.Height) / 2
-			err = image.ExtractArea(left, top, params.Width, params.Height)
+			err := image.ExtractArea(left, top, params.Width, params.Height)
 			if err != nil {
-				return nil, format, err
+				return err
 			}
 		}
 	}
 
-	resized, err := exportImage(image, format)
-	return resized, format, err
+	return nil
+}
+
+// maskImage covers each rectangle with a solid black box or a heavy blur
+func maskImage(image *vips.Image, masks []MaskRect, maskStyle string) error {
+	for _, mask := range masks {
+		// Clamp to the image, the element may extend past the capture
+		left := max(mask.X, 0)
+		top := max(mask.Y, 0)
+		right := min(mask.X+mask.Width, image.Width())
+		bottom := min(mask.Y+mask.Height, image.Height())
+		if right <= left || bottom <= top {
+			continue
+		}
+
+		if maskStyle == MaskStyleBlur {
+			if err := blurArea(image, left, top, right-left, bottom-top); err != nil {
+				return fmt.Errorf("failed to blur masked area: %w", err)
+			}
+			continue
+		}
+
+		ink := make([]float64, image.Bands())
+		if image.HasAlpha() {
+			ink[len(ink)-1] = 255
+		}
+		opts := vips.DefaultDrawRectOptions()
+		opts.Fill = true
+		if err := image.DrawRect(ink, left, top, right-left, bottom-top, opts); err != nil {
+			return fmt.Errorf("failed to draw mask: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// blurArea replaces a region of the image with a blurred copy of itself. The
+// blur is strong enough that text underneath can't be read back.
+func blurArea(image *vips.Image, left, top, width, height int) error {
+	region, err := image.Copy(nil)
+	if err != nil {
+		return err
+	}
+	defer region.Close()
+
+	if err := region.ExtractArea(left, top, width, height); err != nil {
+		return err
+	}
+
+	if err := region.Gaussblur(maskBlurSigma, nil); err != nil {
+		return err
+	}
+
+	return image.Insert(region, left, top, nil)
 }
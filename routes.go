@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/go-rod/rod"
+)
+
+// Route replaces the response to matching requests with a local stub, so a
+// page renders the same data every time
+type Route struct {
+	URL        string            `json:"url,omitempty" jsonschema:"glob matched against the full request URL where * matches any characters, e.g. 'https://api.example.com/v1/*'"`
+	Regex      string            `json:"regex,omitempty" jsonschema:"regular expression matched against the full request URL, instead of url"`
+	Method     string            `json:"method,omitempty" jsonschema:"only match requests with this HTTP method (default: any)"`
+	Status     int               `json:"status,omitempty" jsonschema:"response status code (default: 200)"`
+	Headers    map[string]string `json:"headers,omitempty" jsonschema:"response headers, e.g. {\"Content-Type\": \"application/json\"}"`
+	Body       string            `json:"body,omitempty" jsonschema:"response body as a string"`
+	BodyBase64 string            `json:"body_base64,omitempty" jsonschema:"response body encoded as base64, for binary content like images"`
+	BodyFile   string            `json:"body_file,omitempty" jsonschema:"path of a file holding the response body (command line only)"`
+
+	pattern *regexp.Regexp
+	body    []byte
+}
+
+// ParseRoutes decodes a routes list given either as a JSON array or as an
+// object with a "routes" array. Unknown fields are rejected. Relative
+// body_file paths are read from baseDir. body_file is rejected when baseDir
+// is empty, so remote callers can't read server files.
+func ParseRoutes(data []byte, baseDir string) ([]Route, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	var routes []Route
+	if data[0] == '{' {
+		var body struct {
+			Routes []Route `json:"routes"`
+		}
+		if err := decodeStrictJSON(data, &body); err != nil {
+			return nil, fmt.Errorf("invalid routes JSON: %v", err)
+		}
+		routes = body.Routes
+	} else if err := decodeStrictJSON(data, &routes); err != nil {
+		return nil, fmt.Errorf("invalid routes JSON: %v", err)
+	}
+
+	if err := prepareRoutes(routes, baseDir); err != nil {
+		return nil, err
+	}
+
+	return routes, nil
+}
+
+// decodeStrictJSON decodes a single JSON value into v, rejecting unknown
+// fields so a misspelled key fails instead of being ignored
+func decodeStrictJSON(data []byte, v any) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return fmt.Errorf("unexpected data after JSON value")
+	}
+	return nil
+}
+
+// prepareRoutes validates every route, compiling its pattern and loading its
+// body in place
+func prepareRoutes(routes []Route, baseDir string) error {
+	for i := range routes {
+		if err := prepareRoute(&routes[i], baseDir); err != nil {
+			return fmt.Errorf("invalid route %d: %v", i+1, err)
+		}
+	}
+
+	return nil
+}
+
+func prepareRoute(route *Route, baseDir string) error {
+	var err error
+	switch {
+	case route.URL != "" && route.Regex != "":
+		return fmt.Errorf("url and regex cannot be used together")
+	case route.URL != "":
+		route.pattern, err = regexp.Compile(globToRegex(route.URL))
+	case route.Regex != "":
+		route.pattern, err = regexp.Compile(route.Regex)
+	default:
+		return fmt.Errorf("url or regex is required")
+	}
+	if err != nil {
+		return fmt.Errorf("invalid pattern: %v", err)
+	}
+
+	route.Method = strings.ToUpper(strings.TrimSpace(route.Method))
+
+	if route.Status == 0 {
+		route.Status = http.StatusOK
+	}
+	if route.Status < 100 || route.Status > 599 {
+		return fmt.Errorf("invalid status %d", route.Status)
+	}
+
+	bodies := 0
+	for _, body := range []string{route.Body, route.BodyBase64, route.BodyFile} {
+		if body != "" {
+			bodies++
+		}
+	}
+	if bodies > 1 {
+		return fmt.Errorf("only one of body, body_base64 and body_file can be set")
+	}
+
+	switch {
+	case route.BodyBase64 != "":
+		route.body, err = base64.StdEncoding.DecodeString(route.BodyBase64)
+		if err != nil {
+			return fmt.Errorf("invalid body_base64: %v", err)
+		}
+	case route.BodyFile != "":
+		if baseDir == "" {
+			return fmt.Errorf("body_file is only supported in routes files given on the command line")
+		}
+		path := route.BodyFile
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(baseDir, path)
+		}
+		route.body, err = os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read body_file: %v", err)
+		}
+	default:
+		route.body = []byte(route.Body)
+	}
+
+	return nil
+}
+
+// globToRegex converts a URL glob where * matches any characters to an
+// anchored regular expression
+func globToRegex(glob string) string {
+	parts := strings.Split(glob, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	return "^" + strings.Join(parts, ".*") + "$"
+}
+
+// Matches reports whether the route applies to a request
+func (r *Route) Matches(method, requestURL string) bool {
+	if r.Method != "" && r.Method != method {
+		return false
+	}
+	return r.pattern != nil && r.pattern.MatchString(requestURL)
+}
+
+// matchRoute returns the first route matching the request, or nil. A CORS
+// preflight matches the route of the request it is sent for.
+func matchRoute(ctx *rod.Hijack, routes []Route) *Route {
+	method := ctx.Request.Method()
+	if isPreflight(ctx) {
+		method = strings.ToUpper(ctx.Request.Header("Access-Control-Request-Method"))
+	}
+
+	requestURL := ctx.Request.URL().String()
+	for i := range routes {
+		if routes[i].Matches(method, requestURL) {
+			return &routes[i]
+		}
+	}
+	return nil
+}
+
+// isStubbed reports whether navigating to requestURL is served by a route, so
+// the page URL doesn't need to pass the network policy
+func isStubbed(routes []Route, requestURL string) bool {
+	for i := range routes {
+		if routes[i].Matches(http.MethodGet, requestURL) {
+			return true
+		}
+	}
+	return false
+}
+
+func isPreflight(ctx *rod.Hijack) bool {
+	return ctx.Request.Method() == http.MethodOptions && ctx.Request.Header("Access-Control-Request-Method") != ""
+}
+
+// fulfillRoute answers a hijacked request with the route's stub response.
+// Cross-origin requests from the page are allowed unless the route sets its
+// own CORS headers.
+func fulfillRoute(ctx *rod.Hijack, route *Route) {
+	origin := ctx.Request.Header("Origin")
+
+	// Answer CORS preflights for stubbed cross-origin requests
+	if isPreflight(ctx) {
+		ctx.Response.Payload().ResponseCode = http.StatusNoContent
+		ctx.Response.SetHeader(
+			"Access-Control-Allow-Origin", origin,
+			"Access-Control-Allow-Credentials", "true",
+			"Access-Control-Allow-Methods", ctx.Request.Header("Access-Control-Request-Method"),
+			"Access-Control-Allow-Headers", ctx.Request.Header("Access-Control-Request-Headers"),
+		)
+		return
+	}
+
+	ctx.Response.Payload().ResponseCode = route.Status
+	for name, value := range route.Headers {
+		ctx.Response.SetHeader(name, value)
+	}
+	if origin != "" {
+		if _, exists := lookupHeader(route.Headers, "Access-Control-Allow-Origin"); !exists {
+			ctx.Response.SetHeader(
+				"Access-Control-Allow-Origin", origin,
+				"Access-Control-Allow-Credentials", "true",
+			)
+		}
+	}
+	ctx.Response.SetBody(route.body)
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestGlobToRegex(t *testing.T) {
+	tests := []struct {
+		glob    string
+		url     string
+		matches bool
+	}{
+		{"https://api.example.com/v1/*", "https://api.example.com/v1/users?page=2", true},
+		{"https://api.example.com/v1/*", "https://api.example.com/v2/users", false},
+		{"https://api.example.com/v1/*", "xhttps://api.example.com/v1/users", false},
+		{"*.png", "https://example.com/logo.png", true},
+		{"*.png", "https://example.com/logo.png?v=1", false},
+		{"https://example.com/a.b", "https://example.com/a.b", true},
+		{"https://example.com/a.b", "https://example.com/aXb", false},
+		{"https://example.com/page?id=1", "https://example.com/page?id=1", true},
+		{"https://example.com/page?id=1", "https://example.com/pageXid=1", false},
+		{"https://example.com/a+b", "https://example.com/a+b", true},
+		{"https://example.com/a+b", "https://example.com/aab", false},
+		{"https://example.com/(x)", "https://example.com/(x)", true},
+		{"*", "https://anything.example.com/", true},
+	}
+
+	for _, tt := range tests {
+		pattern := regexp.MustCompile(globToRegex(tt.glob))
+		if got := pattern.MatchString(tt.url); got != tt.matches {
+			t.Errorf("glob %q against %q: expected %v, got %v", tt.glob, tt.url, tt.matches, got)
+		}
+	}
+}
+
+func TestRouteMatches(t *testing.T) {
+	routes, err := ParseRoutes([]byte(`[
+		{"url": "https://api.example.com/*", "method": "post"},
+		{"regex": "^https://cdn\\.example\\.com/.*\\.js$"}
+	]`), "")
+	if err != nil {
+		t.Fatalf("Expected routes to parse, got %v", err)
+	}
+
+	tests := []struct {
+		route   int
+		method  string
+		url     string
+		matches bool
+	}{
+		{0, "POST", "https://api.example.com/items", true},
+		{0, "GET", "https://api.example.com/items", false},
+		{0, "POST", "https://other.example.com/items", false},
+		{1, "GET", "https://cdn.example.com/app.js", true},
+		{1, "POST", "https://cdn.example.com/app.js", true},
+		{1, "GET", "https://cdn.example.com/app.css", false},
+	}
+
+	for _, tt := range tests {
+		if got := routes[tt.route].Matches(tt.method, tt.url); got != tt.matches {
+			t.Errorf("route %d with %s %s: expected %v, got %v", tt.route, tt.method, tt.url, tt.matches, got)
+		}
+	}
+
+	if routes[0].Status != http.StatusOK {
+		t.Errorf("Expected default status %d, got %d", http.StatusOK, routes[0].Status)
+	}
+}
+
+func TestParseRoutesValidation(t *testing.T) {
+	tests := []struct {
+		data  string
+		valid bool
+	}{
+		{`[{"url": "https://example.com/*", "body": "hi"}]`, true},
+		{`{"routes": [{"url": "https://example.com/*"}]}`, true},
+		{`[{"url": "https://example.com/*", "body_base64": "aGk="}]`, true},
+		{`[{"url": "https://example.com/*", "status": 404}]`, true},
+		{`[{"url": "https://example.com/*", "body": "a", "body_base64": "aGk="}]`, false},
+		{`[{"url": "https://example.com/*", "body": "a", "body_file": "a.json"}]`, false},
+		{`[{"url": "https://example.com/*", "body_base64": "aGk=", "body_file": "a.json"}]`, false},
+		{`[{"url": "https://example.com/*", "body_base64": "not base64!"}]`, false},
+		{`[{"url": "https://example.com/*", "regex": ".*"}]`, false},
+		{`[{"body": "hi"}]`, false},
+		{`[{"regex": "("}]`, false},
+		{`[{"url": "https://example.com/*", "status": 700}]`, false},
+		{`[{"url": "https://example.com/*", "stauts": 404}]`, false},
+		{`{"route": [{"url": "https://example.com/*"}]}`, false},
+		{`[{"url": "https://example.com/*"}] []`, false},
+	}
+
+	for _, tt := range tests {
+		_, err := ParseRoutes([]byte(tt.data), "")
+		if tt.valid && err != nil {
+			t.Errorf("%s: expected valid, got %v", tt.data, err)
+		} else if !tt.valid && err == nil {
+			t.Errorf("%s: expected error, got none", tt.data)
+		}
+	}
+}
+
+func TestParseRoutesBodyFile(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "users.json"), []byte(`{"users": []}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	data := []byte(`[{"url": "https://api.example.com/users", "body_file": "users.json"}]`)
+
+	routes, err := ParseRoutes(data, dir)
+	if err != nil {
+		t.Fatalf("Expected body_file to load, got %v", err)
+	}
+	if string(routes[0].body) != `{"users": []}` {
+		t.Errorf("Expected body from file, got %q", routes[0].body)
+	}
+
+	if _, err := ParseRoutes(data, ""); err == nil {
+		t.Error("Expected body_file to be rejected without a base directory")
+	}
+
+	if _, err := ParseRoutes([]byte(`[{"url": "https://api.example.com/*", "body_file": "missing.json"}]`), dir); err == nil {
+		t.Error("Expected error for missing body_file")
+	}
+}
+
+func TestParseRequestBody(t *testing.T) {
+	tests := []struct {
+		body    string
+		actions int
+		routes  int
+		valid   bool
+	}{
+		{`[{"type": "click", "selector": "#a"}]`, 1, 0, true},
+		{`{"actions": [{"type": "click", "selector": "#a"}]}`, 1, 0, true},
+		{`{"routes": [{"url": "https://example.com/*"}]}`, 0, 1, true},
+		{`{"actions": [{"type": "wait", "ms": 5}], "routes": [{"url": "https://example.com/*"}]}`, 1, 1, true},
+		{``, 0, 0, true},
+		{`{"route": [{"url": "https://example.com/*"}]}`, 0, 0, false},
+		{`{"actions": [], "extra": true}`, 0, 0, false},
+		{`{"routes": [{"url": "https://example.com/*", "body_file": "/etc/passwd"}]}`, 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodPost, "/?url=https://example.com", strings.NewReader(tt.body))
+		actions, routes, err := parseRequestBody(r)
+		if !tt.valid {
+			if err == nil {
+				t.Errorf("%s: expected error, got none", tt.body)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: expected valid, got %v", tt.body, err)
+			continue
+		}
+		if len(actions) != tt.actions || len(routes) != tt.routes {
+			t.Errorf("%s: expected %d actions and %d routes, got %d and %d", tt.body, tt.actions, tt.routes, len(actions), len(routes))
+		}
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/?url=https://example.com", strings.NewReader(`{"route": []}`))
+	if _, _, err := parseRequestBody(r); err != nil {
+		t.Errorf("Expected GET body to be ignored, got %v", err)
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"log"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// hijackSession routes every request of a page or child target through
+// handler
+func hijackSession(session *rod.Page, handler func(*rod.Hijack), config *HijackConfig) error {
+	router := session.HijackRequests()
+	if err := router.Add("*", "", handler); err != nil {
+		return err
+	}
+
+	// Enabling auth handling replaces the router's Fetch settings, so it has
+	// to come after the handler is added
+	if config.Proxy.challengesAuth() {
+		if err := handleProxyAuth(session, config.Proxy); err != nil {
+			return err
+		}
+	}
+
+	go router.Run()
+	return nil
+}
+
+// interceptChildTargets calls setup for every cross-site iframe and worker
+// started by the page, and their own children. Each child is paused until
+// setup returns, so none of its requests get past it.
+func interceptChildTargets(page *rod.Page, setup func(*rod.Page)) {
+	go page.EachEvent(func(e *proto.TargetAttachedToTarget) {
+		child := page.Browser().PageFromSession(e.SessionID).Context(page.GetContext())
+		go func() {
+			setup(child)
+			interceptChildTargets(child, setup)
+			if err := (proto.RuntimeRunIfWaitingForDebugger{}).Call(child); err != nil {
+				log.Printf("Error resuming %s target: %v", e.TargetInfo.Type, err)
+			}
+		}()
+	})()
+
+	err := proto.TargetSetAutoAttach{
+		AutoAttach:             true,
+		WaitForDebuggerOnStart: true,
+		Flatten:                true,
+	}.Call(page)
+	if err != nil {
+		log.Printf("Error attaching to child targets: %v", err)
+	}
+}
+
+// blockWebSocketURLs fails every WebSocket connection the session opens
+func blockWebSocketURLs(session *rod.Page) {
+	_ = proto.NetworkEnable{}.Call(session)
+	_ = proto.NetworkSetBlockedURLs{Urls: []string{"ws://*", "wss://*"}}.Call(session)
+}
+
+// checkWebSocketURLs checks every WebSocket the session opens against the
+// policy. WebSockets aren't routed through request interception, so the
+// check runs once the connection has started, and a blocked one is ended by
+// taking the session offline.
+func checkWebSocketURLs(session *rod.Page, checker *networkPolicyChecker, blocked func(url, reason string)) {
+	go session.EachEvent(func(e *proto.NetworkWebSocketCreated) {
+		go func() {
+			err := checker.Check(session.GetContext(), e.URL)
+			if err == nil {
+				return
+			}
+			blocked(e.URL, err.Error())
+
+			err = proto.NetworkEmulateNetworkConditions{
+				Offline:            true,
+				DownloadThroughput: -1,
+				UploadThroughput:   -1,
+			}.Call(session)
+			if err != nil {
+				log.Printf("Error closing blocked WebSocket %s: %v", e.URL, err)
+			}
+		}()
+	})()
+}
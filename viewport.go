@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"math"
 	"strconv"
 	"strings"
 )
@@ -28,3 +29,38 @@ func ParseViewportString(viewport string) (int, int, error) {
 
 	return width, height, nil
 }
+
+// ParseScaleString parses a device scale factor such as 1, 2 or 1.5
+func ParseScaleString(scale string) (float64, error) {
+	if scale == "" {
+		return 0, nil
+	}
+
+	value, err := strconv.ParseFloat(scale, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid scale value, must be a number")
+	}
+
+	if err := validateScale(value); err != nil {
+		return 0, err
+	}
+
+	return value, nil
+}
+
+// validateScale checks a device scale factor, where 0 means the default of 1
+func validateScale(scale float64) error {
+	if math.IsNaN(scale) || math.IsInf(scale, 0) {
+		return fmt.Errorf("scale must be a finite number")
+	}
+
+	if scale < 0 {
+		return fmt.Errorf("scale cannot be negative")
+	}
+
+	if scale > 5 {
+		return fmt.Errorf("scale cannot exceed 5")
+	}
+
+	return nil
+}
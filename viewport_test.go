@@ -0,0 +1,52 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseScaleString(t *testing.T) {
+	tests := []struct {
+		value string
+		scale float64
+		valid bool
+	}{
+		{"", 0, true},
+		{"1", 1, true},
+		{"2.5", 2.5, true},
+		{"5", 5, true},
+		{"0", 0, true},
+		{"5.1", 0, false},
+		{"-1", 0, false},
+		{"abc", 0, false},
+		{"NaN", 0, false},
+		{"nan", 0, false},
+		{"Inf", 0, false},
+		{"+Inf", 0, false},
+		{"-Inf", 0, false},
+	}
+
+	for _, tt := range tests {
+		scale, err := ParseScaleString(tt.value)
+		if !tt.valid {
+			if err == nil {
+				t.Errorf("ParseScaleString(%q): expected error, got %v", tt.value, scale)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("ParseScaleString(%q): expected no error, got %v", tt.value, err)
+		} else if scale != tt.scale {
+			t.Errorf("ParseScaleString(%q): expected %v, got %v", tt.value, tt.scale, scale)
+		}
+	}
+}
+
+func TestValidateScaleRejectsNonFinite(t *testing.T) {
+	for _, scale := range []float64{math.NaN(), math.Inf(1), math.Inf(-1)} {
+		if err := validateScale(scale); err == nil {
+			t.Errorf("Expected error for scale %v", scale)
+		}
+	}
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// defaultWaitTimeout bounds the wait conditions when the request has no
+// timeout, so a selector that never appears can't hang the request forever
+const defaultWaitTimeout = 30 * time.Second
+
+// States accepted by wait_for_selector
+const (
+	WaitStateVisible  = "visible"
+	WaitStateHidden   = "hidden"
+	WaitStateAttached = "attached"
+)
+
+// Page lifecycle points accepted by wait_until
+const (
+	WaitUntilLoad             = "load"
+	WaitUntilDOMContentLoaded = "domcontentloaded"
+	WaitUntilNetworkIdle      = "networkidle"
+)
+
+const (
+	// networkIdleTime is how long there must be no requests in flight for
+	// the network to count as idle
+	networkIdleTime = 500 * time.Millisecond
+
+	// networkIdleMaxWait caps the wait for network idle when the request has
+	// no timeout, pages that poll or stream never go idle so the capture
+	// proceeds after this
+	networkIdleMaxWait = 15 * time.Second
+
+	// maxNetworkIdleMs bounds network_idle_ms and network_idle_max_ms
+	maxNetworkIdleMs = 300000
+
+	networkIdlePollInterval = 50 * time.Millisecond
+)
+
+// normalizeWaitUntil validates a wait_until value, defaulting to load
+func normalizeWaitUntil(waitUntil string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(waitUntil)) {
+	case "", WaitUntilLoad:
+		return WaitUntilLoad, nil
+	case WaitUntilDOMContentLoaded:
+		return WaitUntilDOMContentLoaded, nil
+	case WaitUntilNetworkIdle:
+		return WaitUntilNetworkIdle, nil
+	default:
+		return "", fmt.Errorf("invalid wait_until %q, must be load, domcontentloaded or networkidle", waitUntil)
+	}
+}
+
+// validateNetworkIdle checks the network_idle_ms and network_idle_max_ms
+// values, 0 means the default
+func validateNetworkIdle(idleMs, maxWaitMs int) error {
+	if idleMs < 0 || idleMs > maxNetworkIdleMs {
+		return fmt.Errorf("network_idle_ms must be between 0 and %d", maxNetworkIdleMs)
+	}
+	if maxWaitMs < 0 || maxWaitMs > maxNetworkIdleMs {
+		return fmt.Errorf("network_idle_max_ms must be between 0 and %d", maxNetworkIdleMs)
+	}
+	return nil
+}
+
+// parseNetworkIdleMs parses a network idle query parameter, 0 when empty
+func parseNetworkIdleMs(name, value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	ms, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s value, must be a number of milliseconds", name)
+	}
+	return ms, nil
+}
+
+// normalizeWaitState validates a selector wait state, defaulting to visible
+func normalizeWaitState(state string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(state)) {
+	case "", WaitStateVisible:
+		return WaitStateVisible, nil
+	case WaitStateHidden:
+		return WaitStateHidden, nil
+	case WaitStateAttached:
+		return WaitStateAttached, nil
+	default:
+		return "", fmt.Errorf("invalid wait state %q, must be visible, hidden or attached", state)
+	}
+}
+
+// waitContext returns the page bounded by defaultWaitTimeout if it doesn't
+// already have a deadline from the request timeout
+func waitContext(page *rod.Page) (*rod.Page, func()) {
+	if _, ok := page.GetContext().Deadline(); ok {
+		return page, func() {}
+	}
+
+	ctx, cancel := context.WithTimeout(page.GetContext(), defaultWaitTimeout)
+	return page.Context(ctx), cancel
+}
+
+// waitForSelector polls until the first element matching selector reaches the
+// given state: visible (rendered with a non-zero size), hidden (missing or not
+// rendered) or attached (present in the DOM)
+func waitForSelector(page *rod.Page, selector, state string) error {
+	page, cancel := waitContext(page)
+	defer cancel()
+
+	err := page.Wait(rod.Eval(`(selector, state) => {
+		const element = document.querySelector(selector)
+		if (state === "attached") {
+			return element !== null
+		}
+
+		let visible = false
+		if (element) {
+			const style = window.getComputedStyle(element)
+			const rect = element.getBoundingClientRect()
+			visible = style.visibility !== "hidden" && style.display !== "none" && rect.width > 0 && rect.height > 0
+		}
+
+		return state === "hidden" ? !visible : visible
+	}`, selector, state))
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("timed out waiting for selector %q to be %s", selector, state)
+	}
+	if err != nil {
+		return fmt.Errorf("failed waiting for selector %q: %w", selector, err)
+	}
+
+	return nil
+}
+
+// waitForFunction polls a JavaScript expression until it is truthy. The
+// expression may return a promise, which is awaited on every poll.
+func waitForFunction(page *rod.Page, expression string) error {
+	page, cancel := waitContext(page)
+	defer cancel()
+
+	err := page.Wait(rod.Eval("async () => !!(await (\n" + expression + "\n))"))
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("timed out waiting for function %q", expression)
+	}
+	if err != nil {
+		return fmt.Errorf("wait_for_function expression %q failed: %w", expression, err)
+	}
+
+	return nil
+}
+
+// networkIdleTracker keeps the set of in-flight requests, fed by the network
+// events subscribed in setupRequestHijacking
+type networkIdleTracker struct {
+	inFlight   map[proto.NetworkRequestID]struct{}
+	lastChange time.Time
+	mutex      sync.Mutex
+}
+
+func newNetworkIdleTracker() *networkIdleTracker {
+	return &networkIdleTracker{
+		inFlight:   make(map[proto.NetworkRequestID]struct{}),
+		lastChange: time.Now(),
+	}
+}
+
+func (t *networkIdleTracker) started(requestID proto.NetworkRequestID) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.inFlight[requestID] = struct{}{}
+	t.lastChange = time.Now()
+}
+
+func (t *networkIdleTracker) finished(requestID proto.NetworkRequestID) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if _, exists := t.inFlight[requestID]; exists {
+		delete(t.inFlight, requestID)
+		t.lastChange = time.Now()
+	}
+}
+
+// idleFor returns how long there have been no requests in flight, 0 if any are
+func (t *networkIdleTracker) idleFor() time.Duration {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if len(t.inFlight) > 0 {
+		return 0
+	}
+	return time.Since(t.lastChange)
+}
+
+// waitForNetworkIdle blocks until no requests have been in flight for
+// idleTime. Gives up quietly after maxWait so pages with long polling still
+// get captured, but fails if the request times out. The wait never takes more
+// than three quarters of the time left before the request timeout, and without
+// a timeout or maxWait it is capped at networkIdleMaxWait.
+func waitForNetworkIdle(page *rod.Page, tracker *networkIdleTracker, idleTime, maxWait time.Duration) error {
+	ctx := page.GetContext()
+	if idleTime <= 0 {
+		idleTime = networkIdleTime
+	}
+
+	now := time.Now()
+	deadline := now.Add(networkIdleMaxWait)
+	if maxWait > 0 {
+		deadline = now.Add(maxWait)
+	}
+	if requestDeadline, ok := ctx.Deadline(); ok {
+		// Leave the capture a quarter of the remaining time
+		capped := now.Add(requestDeadline.Sub(now) * 3 / 4)
+		if maxWait <= 0 || capped.Before(deadline) {
+			deadline = capped
+		}
+	}
+
+	ticker := time.NewTicker(networkIdlePollInterval)
+	defer ticker.Stop()
+
+	for tracker.idleFor() < idleTime && time.Now().Before(deadline) {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timed out waiting for network idle: %w", ctx.Err())
+		case <-ticker.C:
+		}
+	}
+
+	return nil
+}
+
+// waitForPage waits for the loaded page to reach the waitUntil lifecycle
+// point. idleMs and maxWaitMs tune networkidle, 0 uses the defaults.
+func waitForPage(page *rod.Page, waitUntil string, idleMs, maxWaitMs int, tracker *networkIdleTracker) error {
+	switch waitUntil {
+	case WaitUntilDOMContentLoaded:
+		return page.Wait(rod.Eval(`() => document.readyState !== "loading"`))
+	case WaitUntilNetworkIdle:
+		if err := page.WaitLoad(); err != nil {
+			return err
+		}
+		return waitForNetworkIdle(page, tracker, time.Duration(idleMs)*time.Millisecond, time.Duration(maxWaitMs)*time.Millisecond)
+	default:
+		return page.WaitLoad()
+	}
+}